@@ -81,7 +81,12 @@ export default defineConfig({
                 text: 'Modules',
                 collapsed: true,
                 items: [
+                  { text: 'fetch', link: '/reference/preprocess/modules/fetch' },
+                  { text: 'generate', link: '/reference/preprocess/modules/generate' },
+                  { text: 'patch', link: '/reference/preprocess/modules/patch' },
                   { text: 'replace', link: '/reference/preprocess/modules/replace' },
+                  { text: 'template', link: '/reference/preprocess/modules/template' },
+                  { text: 'wait', link: '/reference/preprocess/modules/wait' },
                 ],
               },
             ],
@@ -11,6 +11,8 @@ import (
 	"github.com/rakunlabs/into"
 	"github.com/rakunlabs/logi"
 	"github.com/rakunlabs/turna/internal/config"
+	"github.com/rakunlabs/turna/internal/loader"
+	"github.com/rakunlabs/turna/pkg/preprocess/generate"
 	"github.com/rakunlabs/turna/pkg/render"
 	"github.com/rakunlabs/turna/pkg/runner"
 	"github.com/rakunlabs/turna/pkg/server/http"
@@ -107,6 +109,10 @@ func loadConfig(ctx context.Context, visit func(fn func(*pflag.Flag))) error {
 		return fmt.Errorf("unable to load configuration settings: %w", err)
 	}
 
+	if err := config.Include(AppName); err != nil {
+		return fmt.Errorf("unable to include configuration files: %w", err)
+	}
+
 	// override used cmd values
 	visit(func(f *pflag.Flag) {
 		if v, ok := overrideValues[f.Name]; ok {
@@ -144,13 +150,22 @@ func runRoot(ctx context.Context) error {
 	runner.NewStoreReg(wg).SetAsGlobal()
 	into.ShutdownAdd(into.FnWarp(runner.GlobalReg.KillAll), "runner")
 
+	// resolve secret references of the configuration, loads resolve their
+	// data with the resolve option
+	if err := loader.NewResolver().Resolve(ctx, &config.Application); err != nil {
+		return fmt.Errorf("unable to resolve config references: %w", err)
+	}
+
 	// this function will be called after all configs are loaded and dynamically changes
 	call := func(_ context.Context, _ string, data map[string]any) {
-		render.Data = data
+		// keep values of the generate preprocess
+		generate.SetData(data)
 
 		// set service filters
 		for i := range config.Application.Services {
-			config.Application.Services[i].SetFilters()
+			if err := config.Application.Services[i].SetFilters(); err != nil {
+				slog.Error("unable to set service filters, keeping previous filters", "err", err.Error())
+			}
 		}
 
 		// notify
@@ -17,6 +17,7 @@ var (
 
 var Application = struct {
 	LogLevel   string             `cfg:"log_level"`
+	Include    []string           `cfg:"include"`
 	Loads      loader.Configs     `cfg:"loads"`
 	Services   service.Services   `cfg:"services"`
 	Print      string             `cfg:"print"`
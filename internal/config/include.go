@@ -0,0 +1,253 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rakunlabs/turna/internal/loader"
+	"github.com/rakunlabs/turna/pkg/server/http"
+	"github.com/rakunlabs/turna/pkg/service"
+)
+
+const mainConfig = "main config"
+
+// configSuffixes are the extensions of the config file, in lookup order.
+var configSuffixes = []string{".toml", ".yaml", ".yml", ".json"}
+
+// includeFile is the part of the configuration an included file can hold.
+type includeFile struct {
+	Services service.Services `cfg:"services"`
+	Server   struct {
+		HTTP struct {
+			Routers     map[string]http.Router         `cfg:"routers"`
+			Middlewares map[string]http.HTTPMiddleware `cfg:"middlewares"`
+		} `cfg:"http"`
+	} `cfg:"server"`
+}
+
+// Include merges the files matching Application.Include into the
+// application services, routers and middlewares. Relative patterns are
+// resolved against the directory of the config file of name.
+func Include(name string) error {
+	return include(Application.Include, configDir(name), &Application.Services, &Application.Server.HTTP)
+}
+
+// configDir returns the directory of the config file loaded for name. It is
+// empty when no file is used.
+func configDir(name string) string {
+	file := configFile(path.Base(name))
+	if file == "" {
+		return ""
+	}
+
+	return filepath.Dir(file)
+}
+
+// configFile returns the config file of name like the file loader, from
+// CONFIG_FILE_<NAME>, CONFIG_FILE or the first name.<suffix> in the working
+// directory or /etc.
+func configFile(name string) string {
+	if v := os.Getenv("CONFIG_FILE_" + strings.ToUpper(name)); v != "" {
+		return v
+	}
+
+	if v := os.Getenv("CONFIG_FILE"); v != "" {
+		return v
+	}
+
+	for _, folder := range []string{"", "/etc"} {
+		for _, suffix := range configSuffixes {
+			file := filepath.Join(folder, name+suffix)
+			if _, err := os.Stat(file); err == nil {
+				return file
+			}
+		}
+	}
+
+	return ""
+}
+
+// include merges files in pattern order, matches of a pattern in lexical
+// order. A name defined twice is an error.
+func include(patterns []string, dir string, services *service.Services, h *http.HTTP) error {
+	files, err := includeFiles(patterns, dir)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	serviceFrom := make(map[string]string, len(*services))
+	for i := range *services {
+		serviceFrom[(*services)[i].Name] = mainConfig
+	}
+
+	routerFrom := make(map[string]string, len(h.Routers))
+	for name := range h.Routers {
+		routerFrom[name] = mainConfig
+	}
+
+	middlewareFrom := make(map[string]string, len(h.Middlewares))
+	for name := range h.Middlewares {
+		middlewareFrom[name] = mainConfig
+	}
+
+	for _, file := range files {
+		v, err := decodeInclude(file)
+		if err != nil {
+			return err
+		}
+
+		for i := range v.Services {
+			name := v.Services[i].Name
+			if from, ok := serviceFrom[name]; ok {
+				return fmt.Errorf("service %q in %s is already defined in %s", name, file, from)
+			}
+
+			serviceFrom[name] = file
+		}
+
+		*services = append(*services, v.Services...)
+
+		for _, name := range sortedKeys(v.Server.HTTP.Routers) {
+			if from, ok := routerFrom[name]; ok {
+				return fmt.Errorf("router %q in %s is already defined in %s", name, file, from)
+			}
+
+			if h.Routers == nil {
+				h.Routers = make(map[string]http.Router)
+			}
+
+			routerFrom[name] = file
+			h.Routers[name] = v.Server.HTTP.Routers[name]
+		}
+
+		for _, name := range sortedKeys(v.Server.HTTP.Middlewares) {
+			if from, ok := middlewareFrom[name]; ok {
+				return fmt.Errorf("middleware %q in %s is already defined in %s", name, file, from)
+			}
+
+			if h.Middlewares == nil {
+				h.Middlewares = make(map[string]http.HTTPMiddleware)
+			}
+
+			middlewareFrom[name] = file
+			h.Middlewares[name] = v.Server.HTTP.Middlewares[name]
+		}
+	}
+
+	return nil
+}
+
+// includeFiles expands the patterns, relative ones in dir. A file matched
+// twice is used once.
+func includeFiles(patterns []string, dir string) ([]string, error) {
+	var files []string
+
+	seen := make(map[string]struct{})
+
+	for _, pattern := range patterns {
+		if dir != "" && !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+
+		// a plain path must exist, a glob may match nothing
+		if len(matches) == 0 && !strings.ContainsAny(pattern, `*?[\`) {
+			return nil, fmt.Errorf("include file %s not found", pattern)
+		}
+
+		slices.Sort(matches)
+
+		for _, file := range matches {
+			if _, ok := seen[file]; ok {
+				continue
+			}
+
+			seen[file] = struct{}{}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// decodeInclude decodes the file, keys outside of the included sections are
+// rejected so they are not dropped silently.
+func decodeInclude(file string) (*includeFile, error) {
+	var m map[string]any
+	if err := loader.DecodeFile(file, &m); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	if err := checkKeys(file, "", m, map[string]any{
+		"services": nil,
+		"server": map[string]any{
+			"http": map[string]any{
+				"routers":     nil,
+				"middlewares": nil,
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	v := &includeFile{}
+	if err := Decode(m, v); err != nil {
+		return nil, fmt.Errorf("include %s: %w", file, err)
+	}
+
+	return v, nil
+}
+
+// checkKeys returns an error for keys of m not in allowed, a nil allowed value
+// accepts anything under the key.
+func checkKeys(file, prefix string, m, allowed map[string]any) error {
+	for _, key := range sortedKeys(m) {
+		sub, ok := allowed[key]
+		if !ok {
+			return fmt.Errorf("include %s: key %s%s is not supported", file, prefix, key)
+		}
+
+		subAllowed, _ := sub.(map[string]any)
+		if subAllowed == nil {
+			continue
+		}
+
+		subMap, ok := m[key].(map[string]any)
+		if !ok {
+			if m[key] == nil {
+				continue
+			}
+
+			return fmt.Errorf("include %s: key %s%s must be a map", file, prefix, key)
+		}
+
+		if err := checkKeys(file, prefix+key+".", subMap, subAllowed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rakunlabs/turna/pkg/server/http"
+	"github.com/rakunlabs/turna/pkg/service"
+)
+
+func writeIncludes(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		patterns     []string
+		wantServices []string
+		wantRouters  []string
+		wantErr      string
+	}{
+		{
+			name: "merge in order",
+			files: map[string]string{
+				"conf.d/b.yaml": "services:\n  - name: b\n",
+				"conf.d/a.yaml": "services:\n  - name: a\nserver:\n  http:\n    routers:\n      api:\n        path:\n          - /api\n",
+				"extra.json":    `{"services": [{"name": "c"}]}`,
+			},
+			patterns:     []string{"conf.d/*.yaml", "extra.json", "conf.d/a.yaml"},
+			wantServices: []string{"main", "a", "b", "c"},
+			wantRouters:  []string{"api", "main"},
+		},
+		{
+			name: "duplicate service",
+			files: map[string]string{
+				"a.yaml": "services:\n  - name: main\n",
+			},
+			patterns: []string{"*.yaml"},
+			wantErr:  `service "main" in`,
+		},
+		{
+			name: "duplicate router across files",
+			files: map[string]string{
+				"a.yaml": "server:\n  http:\n    routers:\n      api: {}\n",
+				"b.yaml": "server:\n  http:\n    routers:\n      api: {}\n",
+			},
+			patterns: []string{"*.yaml"},
+			wantErr:  `router "api" in`,
+		},
+		{
+			name: "unsupported key",
+			files: map[string]string{
+				"a.yaml": "server:\n  entrypoints: {}\n",
+			},
+			patterns: []string{"*.yaml"},
+			wantErr:  "key server.entrypoints is not supported",
+		},
+		{
+			name:     "missing file",
+			patterns: []string{"missing.yaml"},
+			wantErr:  "not found",
+		},
+		{
+			name:         "empty glob",
+			patterns:     []string{"conf.d/*.yaml"},
+			wantServices: []string{"main"},
+			wantRouters:  []string{"main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeIncludes(t, tt.files)
+
+			services := service.Services{{Name: "main"}}
+			h := http.HTTP{Routers: map[string]http.Router{"main": {}}}
+
+			// relative patterns are resolved in the config file directory
+			err := include(tt.patterns, dir, &services, &h)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("include() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("include() error = %v", err)
+			}
+
+			gotServices := make([]string, 0, len(services))
+			for i := range services {
+				gotServices = append(gotServices, services[i].Name)
+			}
+
+			if strings.Join(gotServices, ",") != strings.Join(tt.wantServices, ",") {
+				t.Errorf("include() services = %v, want %v", gotServices, tt.wantServices)
+			}
+
+			if got := sortedKeys(h.Routers); strings.Join(got, ",") != strings.Join(tt.wantRouters, ",") {
+				t.Errorf("include() routers = %v, want %v", got, tt.wantRouters)
+			}
+		})
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	dir := writeIncludes(t, map[string]string{
+		"turna.yaml":     "services: []\n",
+		"app/other.yaml": "services: []\n",
+	})
+
+	t.Setenv("CONFIG_FILE", filepath.Join(dir, "turna.yaml"))
+
+	if got := configDir("turna"); got != dir {
+		t.Errorf("configDir() = %q, want %q", got, dir)
+	}
+
+	t.Setenv("CONFIG_FILE_TURNA", filepath.Join(dir, "app", "other.yaml"))
+
+	if got := configDir("turna"); got != filepath.Join(dir, "app") {
+		t.Errorf("configDir() = %q, want %q", got, filepath.Join(dir, "app"))
+	}
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("CONFIG_FILE_TURNA", "")
+	t.Chdir(dir)
+
+	if got := configDir("turna"); got != "." {
+		t.Errorf("configDir() = %q, want %q", got, ".")
+	}
+
+	if got := configDir("missing"); got != "" {
+		t.Errorf("configDir() = %q, want empty", got)
+	}
+}
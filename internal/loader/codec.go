@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -73,7 +75,8 @@ func (tomlCodec) Decode(r io.Reader, v any) error {
 	return nil
 }
 
-// codecByName returns the codec for a named codec (YAML, JSON, TOML).
+// codecByName returns the codec for a named codec (YAML, JSON, TOML, DOTENV,
+// PROPERTIES, INI, HCL).
 // Empty name defaults to YAML.
 func codecByName(name string) (codec, error) {
 	switch strings.ToUpper(name) {
@@ -83,6 +86,14 @@ func codecByName(name string) (codec, error) {
 		return jsonCodec{}, nil
 	case "TOML":
 		return tomlCodec{}, nil
+	case "DOTENV", "ENV":
+		return dotenvCodec{}, nil
+	case "PROPERTIES":
+		return propertiesCodec{}, nil
+	case "INI":
+		return iniCodec{}, nil
+	case "HCL":
+		return hclCodec{}, nil
 	default:
 		return nil, fmt.Errorf("codec %s not found", name)
 	}
@@ -97,6 +108,14 @@ func codecByExt(ext string) (codec, error) {
 		return jsonCodec{}, nil
 	case ".toml":
 		return tomlCodec{}, nil
+	case ".env":
+		return dotenvCodec{}, nil
+	case ".properties":
+		return propertiesCodec{}, nil
+	case ".ini":
+		return iniCodec{}, nil
+	case ".hcl":
+		return hclCodec{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported file extension %q", ext)
 	}
@@ -114,6 +133,10 @@ func codecNameByContentType(contentType string) string {
 		return "TOML"
 	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
 		return "YAML"
+	case "text/x-java-properties":
+		return "PROPERTIES"
+	case "application/hcl", "text/x-hcl":
+		return "HCL"
 	default:
 		return "YAML"
 	}
@@ -128,3 +151,23 @@ func decodeContent(name string, data []byte, v any) error {
 
 	return c.Decode(strings.NewReader(string(data)), v)
 }
+
+// DecodeFile decodes the file into v with the codec of its extension.
+func DecodeFile(name string, v any) error {
+	c, err := codecByExt(filepath.Ext(name))
+	if err != nil {
+		return fmt.Errorf("file %s: %w", name, err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if err := c.Decode(f, v); err != nil {
+		return fmt.Errorf("failed to decode file %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,653 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// dotenvCodec reads and writes .env files.
+//
+// Decoded keys are kept flat, nested maps are written as upper case keys
+// joined with _ as DB_HOST.
+type dotenvCodec struct{}
+
+func (dotenvCodec) Encode(w io.Writer, v any) error {
+	m, err := toMap(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode DOTENV: %w", err)
+	}
+
+	flat := map[string]string{}
+	flattenMap("", "_", m, flat)
+
+	bw := bufio.NewWriter(w)
+	for _, k := range sortedKeys(flat) {
+		fmt.Fprintf(bw, "%s=%s\n", dotenvKey(k), dotenvValue(flat[k]))
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to encode DOTENV: %w", err)
+	}
+
+	return nil
+}
+
+func (dotenvCodec) Decode(r io.Reader, v any) error {
+	m := map[string]interface{}{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("failed to decode DOTENV: line %d: missing =", line)
+		}
+
+		value, err := dotenvUnquote(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("failed to decode DOTENV: line %d: %w", line, err)
+		}
+
+		m[strings.TrimSpace(key)] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to decode DOTENV: %w", err)
+	}
+
+	return fromMap(m, v)
+}
+
+func dotenvKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, k)
+}
+
+// dotenvValue quotes v when needed. Values with $ are single quoted so
+// docker compose, godotenv and shells don't expand them, otherwise only the
+// \n, \", \\ and \$ escapes are written in double quotes.
+func dotenvValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r\"'#$\\`") {
+		return v
+	}
+
+	if strings.Contains(v, "$") && !strings.ContainsAny(v, "'\n\r") {
+		return "'" + v + "'"
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for _, r := range v {
+		switch r {
+		case '\n':
+			b.WriteString(`\n`)
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func dotenvUnquote(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		end := closingQuote(v)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote")
+		}
+
+		return dotenvUnescape(v[1:end]), nil
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote")
+		}
+
+		return v[1 : end+1], nil
+	default:
+		return stripInlineComment(v, " #"), nil
+	}
+}
+
+// dotenvUnescape replaces the escapes of a double quoted value, unknown
+// escapes are kept as written.
+func dotenvUnescape(v string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+
+			continue
+		}
+
+		i++
+
+		switch v[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$', '`':
+			b.WriteByte(v[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(v[i])
+		}
+	}
+
+	return b.String()
+}
+
+// closingQuote returns the index of the double quote closing v[0].
+func closingQuote(v string) int {
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// propertiesCodec reads and writes Java .properties files.
+//
+// Keys are nested by dots, so db.host=x is decoded as db/host.
+type propertiesCodec struct{}
+
+func (propertiesCodec) Encode(w io.Writer, v any) error {
+	m, err := toMap(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode PROPERTIES: %w", err)
+	}
+
+	flat := map[string]string{}
+	flattenMap("", ".", m, flat)
+
+	bw := bufio.NewWriter(w)
+	for _, k := range sortedKeys(flat) {
+		fmt.Fprintf(bw, "%s=%s\n", propertiesEscape(k, true), propertiesEscape(flat[k], false))
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to encode PROPERTIES: %w", err)
+	}
+
+	return nil
+}
+
+func (propertiesCodec) Decode(r io.Reader, v any) error {
+	m := map[string]interface{}{}
+
+	scanner := bufio.NewScanner(r)
+	logical := ""
+	for scanner.Scan() {
+		text := strings.TrimLeftFunc(scanner.Text(), unicode.IsSpace)
+		if logical == "" && (text == "" || text[0] == '#' || text[0] == '!') {
+			continue
+		}
+
+		// odd number of trailing backslashes continues the line
+		if n := len(text) - len(strings.TrimRight(text, `\`)); n%2 == 1 {
+			logical += text[:len(text)-1]
+
+			continue
+		}
+
+		logical += text
+
+		key, value := propertiesSplit(logical)
+		logical = ""
+
+		setPath(m, strings.Split(key, "."), value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to decode PROPERTIES: %w", err)
+	}
+
+	if logical != "" {
+		key, value := propertiesSplit(logical)
+		setPath(m, strings.Split(key, "."), value)
+	}
+
+	return fromMap(m, v)
+}
+
+// propertiesSplit splits a logical line at the first unescaped =, : or
+// whitespace and unescapes both sides.
+func propertiesSplit(line string) (string, string) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+
+			continue
+		}
+
+		if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' {
+			break
+		}
+	}
+
+	if i > len(line) {
+		i = len(line)
+	}
+
+	key := line[:i]
+	rest := strings.TrimLeft(line[i:], " \t")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+
+	return propertiesUnescape(key), propertiesUnescape(rest)
+}
+
+func propertiesUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if r, ok := propertiesUnicode(s[i+1:]); ok {
+				i += 4
+				// a supplementary rune is written as a surrogate pair
+				if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+					if low, ok := propertiesUnicode(s[i+3:]); ok {
+						if pair := utf16.DecodeRune(r, low); pair != unicode.ReplacementChar {
+							r = pair
+							i += 6
+						}
+					}
+				}
+
+				b.WriteRune(r)
+
+				continue
+			}
+
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// propertiesUnicode reads the 4 hex digits of a \u escape.
+func propertiesUnicode(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+
+	r, err := strconv.ParseUint(s[:4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return rune(r), true
+}
+
+// propertiesEscape escapes s for a .properties file. Files are read as
+// ISO-8859-1 by Java, so control characters and runes above 0x7E are written
+// as \uXXXX escapes.
+func propertiesEscape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+
+			b.WriteRune(r)
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+
+			b.WriteRune(r)
+		default:
+			if r >= 0x20 && r <= 0x7E {
+				b.WriteRune(r)
+
+				continue
+			}
+
+			if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+				fmt.Fprintf(&b, `\u%04X\u%04X`, r1, r2)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// iniCodec reads and writes INI files.
+//
+// Keys before the first section are top level, sections are nested maps and
+// dotted section names as [db.replica] are nested further.
+type iniCodec struct{}
+
+func (iniCodec) Encode(w io.Writer, v any) error {
+	m, err := toMap(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode INI: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	iniWriteSection(bw, "", m)
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to encode INI: %w", err)
+	}
+
+	return nil
+}
+
+func iniWriteSection(w io.Writer, name string, m map[string]interface{}) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var sections []string
+
+	if name != "" {
+		fmt.Fprintf(w, "[%s]\n", name)
+	}
+
+	for _, k := range keys {
+		if _, ok := m[k].(map[string]interface{}); ok {
+			sections = append(sections, k)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%s = %s\n", k, iniValue(flatValue(m[k])))
+	}
+
+	for _, k := range sections {
+		fullName := k
+		if name != "" {
+			fullName = name + "." + k
+		}
+
+		fmt.Fprintln(w)
+		iniWriteSection(w, fullName, m[k].(map[string]interface{}))
+	}
+}
+
+// iniValue writes v as is when it reads back unchanged, otherwise it is
+// quoted with Go escapes which are replaced by iniUnquote.
+func iniValue(v string) string {
+	if v != strings.TrimSpace(v) || strings.HasPrefix(v, `"`) || strings.Contains(v, " ;") ||
+		strings.Contains(v, " #") || strings.IndexFunc(v, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+// iniUnquote reads a double quoted value written by iniValue, unknown
+// escapes are kept as written and text after the closing quote is ignored.
+func iniUnquote(v string) (string, error) {
+	end := closingQuote(v)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated quote")
+	}
+
+	var b strings.Builder
+
+	for s := v[1:end]; s != ""; {
+		value, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			b.WriteByte(s[0])
+			s = s[1:]
+
+			continue
+		}
+
+		if value < utf8.RuneSelf || multibyte {
+			b.WriteRune(value)
+		} else {
+			b.WriteByte(byte(value))
+		}
+
+		s = tail
+	}
+
+	return b.String(), nil
+}
+
+func (iniCodec) Decode(r io.Reader, v any) error {
+	m := map[string]interface{}{}
+	var section []string
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+
+		if text[0] == '[' {
+			if !strings.HasSuffix(text, "]") {
+				return fmt.Errorf("failed to decode INI: line %d: invalid section", line)
+			}
+
+			section = strings.Split(strings.TrimSpace(text[1:len(text)-1]), ".")
+			ensurePath(m, section)
+
+			continue
+		}
+
+		i := strings.IndexAny(text, "=:")
+		if i < 0 {
+			return fmt.Errorf("failed to decode INI: line %d: missing =", line)
+		}
+
+		value := strings.TrimSpace(text[i+1:])
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := iniUnquote(value)
+			if err != nil {
+				return fmt.Errorf("failed to decode INI: line %d: %w", line, err)
+			}
+
+			value = unquoted
+		} else {
+			value = stripInlineComment(value, " ;", " #")
+		}
+
+		key := append(append([]string{}, section...), strings.TrimSpace(text[:i]))
+		setPath(m, key, value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to decode INI: %w", err)
+	}
+
+	return fromMap(m, v)
+}
+
+// stripInlineComment cuts v at the first comment marker.
+func stripInlineComment(v string, markers ...string) string {
+	for _, marker := range markers {
+		if i := strings.Index(v, marker); i >= 0 {
+			v = v[:i]
+		}
+	}
+
+	return strings.TrimSpace(v)
+}
+
+// ensurePath creates the nested maps of keys in m.
+func ensurePath(m map[string]interface{}, keys []string) {
+	for _, k := range keys {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+
+		m = next
+	}
+}
+
+// flattenMap flattens nested maps into out with keys joined by separator.
+func flattenMap(prefix, separator string, m map[string]interface{}, out map[string]string) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + separator + k
+		}
+
+		if vm, ok := v.(map[string]interface{}); ok {
+			flattenMap(key, separator, vm, out)
+
+			continue
+		}
+
+		out[key] = flatValue(v)
+	}
+}
+
+// flatValue formats a value for flat formats, lists and maps as JSON.
+func flatValue(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(vv), 'f', -1, 32)
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(vv)
+		if err != nil {
+			return fmt.Sprint(vv)
+		}
+
+		return string(data)
+	default:
+		return fmt.Sprint(vv)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// toMap returns v as a map, converting other values through JSON.
+func toMap(v any) (map[string]interface{}, error) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return vv, nil
+	case *map[string]interface{}:
+		return *vv, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("value is not a map: %w", err)
+	}
+
+	return m, nil
+}
+
+// fromMap stores the decoded map m in v.
+func fromMap(m map[string]interface{}, v any) error {
+	switch vv := v.(type) {
+	case *map[string]interface{}:
+		*vv = m
+	case *interface{}:
+		*vv = m
+	default:
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(data, v)
+	}
+
+	return nil
+}
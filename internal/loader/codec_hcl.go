@@ -0,0 +1,125 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/hcl"
+)
+
+// hclCodec reads and writes HCL (v1) files.
+//
+// Blocks are decoded as nested maps and nested maps are written as blocks.
+type hclCodec struct{}
+
+var hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func (hclCodec) Encode(w io.Writer, v any) error {
+	m, err := toMap(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode HCL: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := hclWriteBody(bw, m, ""); err != nil {
+		return fmt.Errorf("failed to encode HCL: %w", err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to encode HCL: %w", err)
+	}
+
+	return nil
+}
+
+func hclWriteBody(w io.Writer, m map[string]interface{}, indent string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if !hclIdentifier.MatchString(k) {
+			key = strconv.Quote(k)
+		}
+
+		if vm, ok := m[k].(map[string]interface{}); ok {
+			fmt.Fprintf(w, "%s%s {\n", indent, key)
+			if err := hclWriteBody(w, vm, indent+"  "); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "%s}\n", indent)
+
+			continue
+		}
+
+		if m[k] == nil {
+			continue
+		}
+
+		// JSON values are valid HCL for strings, numbers, bools and lists
+		data, err := json.Marshal(m[k])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s%s = %s\n", indent, key, data)
+	}
+
+	return nil
+}
+
+func (hclCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to decode HCL: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := hcl.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed to decode HCL: %w", err)
+	}
+
+	return fromMap(hclNormalize(m).(map[string]interface{}), v)
+}
+
+// hclNormalize unwraps blocks, which HCL decodes as a list of maps, into maps.
+// Repeated blocks stay as a list.
+func hclNormalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = hclNormalize(item)
+		}
+
+		return vv
+	case []map[string]interface{}:
+		if len(vv) == 1 {
+			return hclNormalize(vv[0])
+		}
+
+		out := make([]interface{}, 0, len(vv))
+		for _, item := range vv {
+			out = append(out, hclNormalize(item))
+		}
+
+		return out
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = hclNormalize(item)
+		}
+
+		return vv
+	default:
+		return v
+	}
+}
@@ -0,0 +1,287 @@
+package loader
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodec_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   string
+		content string
+		want    map[string]interface{}
+	}{
+		{
+			name:  "dotenv",
+			codec: "DOTENV",
+			content: `# comment
+export APP_NAME=turna
+DB_HOST = localhost # inline
+DB_PASS="p a\"ss#"
+RAW='x $y'
+EMPTY=
+`,
+			want: map[string]interface{}{
+				"APP_NAME": "turna",
+				"DB_HOST":  "localhost",
+				"DB_PASS":  `p a"ss#`,
+				"RAW":      "x $y",
+				"EMPTY":    "",
+			},
+		},
+		{
+			name:  "properties",
+			codec: "PROPERTIES",
+			content: `# comment
+! other comment
+db.host=localhost
+db.port : 5432
+app.name turna
+app.desc = multi \
+    line
+key\=x=A
+`,
+			want: map[string]interface{}{
+				"db": map[string]interface{}{
+					"host": "localhost",
+					"port": "5432",
+				},
+				"app": map[string]interface{}{
+					"name": "turna",
+					"desc": "multi line",
+				},
+				"key=x": "A",
+			},
+		},
+		{
+			name:  "ini",
+			codec: "INI",
+			content: `; comment
+name = turna
+
+[db]
+host = localhost ; inline
+pass = "a;b"
+
+[db.replica]
+host: replica
+
+[empty]
+`,
+			want: map[string]interface{}{
+				"name": "turna",
+				"db": map[string]interface{}{
+					"host": "localhost",
+					"pass": "a;b",
+					"replica": map[string]interface{}{
+						"host": "replica",
+					},
+				},
+				"empty": map[string]interface{}{},
+			},
+		},
+		{
+			name:  "hcl",
+			codec: "HCL",
+			content: `name = "turna"
+port = 8080
+
+db {
+  host = "localhost"
+  tags = ["a", "b"]
+}
+`,
+			want: map[string]interface{}{
+				"name": "turna",
+				"port": 8080,
+				"db": map[string]interface{}{
+					"host": "localhost",
+					"tags": []interface{}{"a", "b"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			if err := decodeContent(tt.codec, []byte(tt.content), &got); err != nil {
+				t.Fatalf("decodeContent() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeContent() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodec_Encode(t *testing.T) {
+	data := map[string]interface{}{
+		"name": "turna app",
+		"db": map[string]interface{}{
+			"host": "localhost",
+			"port": 5432,
+			"replica": map[string]interface{}{
+				"host": "replica",
+			},
+		},
+		"ratio": 0.5,
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{
+			ext: ".env",
+			want: `DB_HOST=localhost
+DB_PORT=5432
+DB_REPLICA_HOST=replica
+NAME="turna app"
+RATIO=0.5
+TAGS="[\"a\",\"b\"]"
+`,
+		},
+		{
+			ext: ".properties",
+			want: `db.host=localhost
+db.port=5432
+db.replica.host=replica
+name=turna app
+ratio=0.5
+tags=["a","b"]
+`,
+		},
+		{
+			ext: ".ini",
+			want: `name = turna app
+ratio = 0.5
+tags = ["a","b"]
+
+[db]
+host = localhost
+port = 5432
+
+[db.replica]
+host = replica
+`,
+		},
+		{
+			ext: ".hcl",
+			want: `db {
+  host = "localhost"
+  port = 5432
+  replica {
+    host = "replica"
+  }
+}
+name = "turna app"
+ratio = 0.5
+tags = ["a","b"]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			c, err := codecByExt(tt.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := c.Encode(&buf, data); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Encode() = %s, want %s", got, tt.want)
+			}
+
+			// round trip back to a map
+			var got map[string]interface{}
+			if err := c.Decode(strings.NewReader(buf.String()), &got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			if len(got) == 0 {
+				t.Errorf("Decode() returned empty map")
+			}
+		})
+	}
+}
+
+func TestDotenvValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "", want: `""`},
+		{value: "pa$word", want: `'pa$word'`},
+		{value: "it's $5", want: `"it's \$5"`},
+		{value: "line\n$x", want: `"line\n\$x"`},
+		{value: `say "hi" \ café`, want: `"say \"hi\" \\ café"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := dotenvValue(tt.value)
+			if got != tt.want {
+				t.Errorf("dotenvValue() = %s, want %s", got, tt.want)
+			}
+
+			back, err := dotenvUnquote(got)
+			if err != nil {
+				t.Fatalf("dotenvUnquote() error = %v", err)
+			}
+
+			if back != tt.value {
+				t.Errorf("dotenvUnquote() = %q, want %q", back, tt.value)
+			}
+		})
+	}
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	values := []string{
+		"café",
+		"snow ☃ and 😀",
+		"tab\tbell\a\x00nul",
+		"line\nfeed\r\fend",
+		` lead "quote" \ trail `,
+		`C:\path`,
+	}
+
+	for _, ext := range []string{".properties", ".ini"} {
+		for _, value := range values {
+			t.Run(ext+" "+value, func(t *testing.T) {
+				c, err := codecByExt(ext)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var buf bytes.Buffer
+				if err := c.Encode(&buf, map[string]interface{}{"key": value}); err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+
+				if ext == ".properties" && strings.IndexFunc(buf.String(), func(r rune) bool { return r > 0x7E }) >= 0 {
+					t.Errorf("Encode() = %q, want ASCII only", buf.String())
+				}
+
+				var got map[string]interface{}
+				if err := c.Decode(strings.NewReader(buf.String()), &got); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+
+				if got["key"] != value {
+					t.Errorf("Decode() = %q, want %q, encoded %q", got["key"], value, buf.String())
+				}
+			})
+		}
+	}
+}
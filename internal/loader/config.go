@@ -1,6 +1,10 @@
 package loader
 
-import "time"
+import (
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
 
 // Configs is a list of load configurations.
 type Configs []Config
@@ -8,26 +12,87 @@ type Configs []Config
 // Config is a single load configuration.
 type Config struct {
 	// Name for export value, default is empty.
-	Name       string          `cfg:"name"`
-	Export     string          `cfg:"export"`
+	Name string `cfg:"name"`
+	// Export is a file path, codec is selected by the extension.
+	Export string `cfg:"export"`
+	// Exports are additional export targets.
+	Exports    []ConfigExport  `cfg:"exports"`
 	FilePerm   string          `cfg:"file_perm"`
 	FolderPerm string          `cfg:"folder_perm"`
 	Statics    []ConfigStatic  `cfg:"statics"`
 	Dynamics   []ConfigDynamic `cfg:"dynamics"`
+	// Schema validates the merged map before export.
+	Schema *ConfigSchema `cfg:"schema"`
+	// Resolve replaces the secret references in the loaded data before the
+	// validation and export, default is false.
+	Resolve bool `cfg:"resolve"`
+
+	schema   *jsonschema.Schema
+	resolver *Resolver
+}
+
+// ConfigExport is a file written after every successful load.
+type ConfigExport struct {
+	// Path of the file, written atomically.
+	Path string `cfg:"path"`
+	// Codec overrides the codec selected by the path extension.
+	Codec string `cfg:"codec"`
+	// Template renders the file content instead of the codec, with the loaded values.
+	Template string `cfg:"template"`
+	// FilePerm and FolderPerm default to the load's permissions.
+	FilePerm   string `cfg:"file_perm"`
+	FolderPerm string `cfg:"folder_perm"`
+	// Hook runs after the file content changed.
+	Hook *ConfigExportHook `cfg:"hook"`
+}
+
+// ConfigExportHook runs a command and notifies a service after an export.
+type ConfigExportHook struct {
+	// Command to run, as ["nginx", "-s", "reload"].
+	Command []string `cfg:"command"`
+	// Timeout of the command, default is 30s.
+	Timeout time.Duration `cfg:"timeout"`
+	// Service is the name of a service in services.
+	Service string `cfg:"service"`
+	// Signal to send to the service, as SIGHUP.
+	Signal string `cfg:"signal"`
+	// Restart the service instead of sending a signal.
+	Restart bool `cfg:"restart"`
+}
+
+// ConfigSchema is a JSON Schema from a file or inline.
+type ConfigSchema struct {
+	// File is a JSON or YAML schema file.
+	File string `cfg:"file"`
+	// Inline is the schema itself.
+	Inline map[string]interface{} `cfg:"inline"`
 }
 
 // ConfigStatic is a source loaded once at startup.
 type ConfigStatic struct {
-	Consul  *ConfigConsul  `cfg:"consul"`
-	Vault   *ConfigVault   `cfg:"vault"`
-	File    *ConfigFile    `cfg:"file"`
-	Content *ConfigContent `cfg:"content"`
-	HTTP    *ConfigHTTP    `cfg:"http"`
+	Consul        *ConfigConsul        `cfg:"consul"`
+	Vault         *ConfigVault         `cfg:"vault"`
+	File          *ConfigFile          `cfg:"file"`
+	Content       *ConfigContent       `cfg:"content"`
+	HTTP          *ConfigHTTP          `cfg:"http"`
+	Env           *ConfigEnv           `cfg:"env"`
+	ConsulCatalog *ConfigConsulCatalog `cfg:"consul_catalog"`
+	Etcd          *ConfigEtcd          `cfg:"etcd"`
 }
 
 // ConfigDynamic is a source watched/reloaded while running.
 type ConfigDynamic struct {
 	Consul *ConfigConsul `cfg:"consul"`
+	// File watches the file and reloads it on change.
+	File *ConfigFile `cfg:"file"`
+	// HTTP polls the endpoint and reloads it on change.
+	HTTP *ConfigHTTP `cfg:"http"`
+	// Vault watches KVv2 versions or keeps leased secrets renewed.
+	Vault *ConfigVault `cfg:"vault"`
+	// ConsulCatalog watches service instances with blocking queries.
+	ConsulCatalog *ConfigConsulCatalog `cfg:"consul_catalog"`
+	// Etcd watches a key or prefix.
+	Etcd *ConfigEtcd `cfg:"etcd"`
 }
 
 type ConfigConsul struct {
@@ -39,7 +104,42 @@ type ConfigConsul struct {
 	PathPrefix string `cfg:"path_prefix"`
 	// Raw to load as raw, don't mix with other loaders.
 	Raw bool `cfg:"raw"`
-	// Codec YAML,JSON,TOML default is YAML.
+	// Codec YAML,JSON,TOML,DOTENV,PROPERTIES,INI,HCL default is YAML.
+	Codec string `cfg:"codec"`
+	// InnerPath is get the inner path from response, / separated as db/settings.
+	// Cannot work with Raw.
+	InnerPath string `cfg:"inner_path"`
+	// Map is the wrapper map, / separated as db/settings.
+	Map string `cfg:"map"`
+	// Template to run go template after the load.
+	Template bool `cfg:"template"`
+	// Base64 to decode the content.
+	Base64 bool `cfg:"base64"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
+}
+
+type ConfigEtcd struct {
+	// Name for export, default is empty.
+	Name string `cfg:"name"`
+	// Endpoints default is ETCD_ENDPOINTS env comma separated or localhost:2379.
+	Endpoints []string `cfg:"endpoints"`
+	// Path is the key, or the key prefix with Prefix.
+	Path string `cfg:"path"`
+	// Prefix reads all keys under Path as a nested map split by /.
+	Prefix bool `cfg:"prefix"`
+	// Username and Password for etcd auth.
+	Username string `cfg:"username"`
+	Password string `cfg:"password"`
+	// TLS client configuration.
+	TLS *ConfigEtcdTLS `cfg:"tls"`
+	// DialTimeout default is 5s.
+	DialTimeout time.Duration `cfg:"dial_timeout"`
+	// Raw to load as raw, don't mix with other loaders.
+	Raw bool `cfg:"raw"`
+	// Codec YAML,JSON,TOML,DOTENV,PROPERTIES,INI,HCL default is YAML.
+	// Not used with Prefix.
 	Codec string `cfg:"codec"`
 	// InnerPath is get the inner path from response, / separated as db/settings.
 	// Cannot work with Raw.
@@ -50,6 +150,39 @@ type ConfigConsul struct {
 	Template bool `cfg:"template"`
 	// Base64 to decode the content.
 	Base64 bool `cfg:"base64"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	Merge string `cfg:"merge"`
+}
+
+type ConfigEtcdTLS struct {
+	// CAFile to verify the server certificate.
+	CAFile string `cfg:"ca_file"`
+	// CertFile and KeyFile for client certificate auth.
+	CertFile string `cfg:"cert_file"`
+	KeyFile  string `cfg:"key_file"`
+	// ServerName overrides the server name to verify.
+	ServerName         string `cfg:"server_name"`
+	InsecureSkipVerify bool   `cfg:"insecure_skip_verify"`
+}
+
+// ConfigConsulCatalog loads instances of consul services as
+// service name -> list of instances with id, service, node, address, port,
+// tags, meta and datacenter.
+type ConfigConsulCatalog struct {
+	// Name for export, default is empty.
+	Name string `cfg:"name"`
+	// Services to load.
+	Services []string `cfg:"services"`
+	// Tags filters instances having all tags.
+	Tags []string `cfg:"tags"`
+	// Datacenter default is the agent's datacenter.
+	Datacenter string `cfg:"datacenter"`
+	// All includes instances with failing health checks, default is only passing.
+	All bool `cfg:"all"`
+	// Map is the wrapper map, / separated as db/settings.
+	Map string `cfg:"map"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	Merge string `cfg:"merge"`
 }
 
 type ConfigVault struct {
@@ -59,7 +192,21 @@ type ConfigVault struct {
 	// PathPrefix default is empty, path_prefix is must!
 	PathPrefix string `cfg:"path_prefix"`
 	// AppRoleBasePath default is auth/approle/login, not need to set.
+	// Used when Auth is not set.
 	AppRoleBasePath string `cfg:"app_role_base_path"`
+	// Auth is the login method, default is AppRole with VAULT_ROLE_ID and
+	// VAULT_ROLE_SECRET env vars.
+	Auth *ConfigVaultAuth `cfg:"auth"`
+	// Method is how the secret is read, default is kv.
+	//  - kv reads Path from the KVv2 mount PathPrefix.
+	//  - read reads PathPrefix/Path, as database/creds/app.
+	//  - write writes Data to PathPrefix/Path, as pki/issue/app.
+	Method string `cfg:"method"`
+	// Data is the request body of the write method.
+	Data map[string]interface{} `cfg:"data"`
+	// Interval to check the KVv2 version when used as a dynamic source,
+	// default is 30s. Secrets without lease or expiration are re-read with it.
+	Interval time.Duration `cfg:"interval"`
 	// InnerPath is get the inner path from vault response, / separated as db/settings.
 	InnerPath string `cfg:"inner_path"`
 	// Map is the wrapper map, / separated as db/settings.
@@ -68,6 +215,34 @@ type ConfigVault struct {
 	Template bool `cfg:"template"`
 	// Base64 to decode the content.
 	Base64 bool `cfg:"base64"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
+}
+
+// ConfigVaultAuth is the login of a vault source. Loads with the same auth
+// share the token, which is renewed while dynamic sources run.
+type ConfigVaultAuth struct {
+	// Method is approle, kubernetes, jwt or token, default is approle.
+	Method string `cfg:"method"`
+	// Path is the auth mount path, default is the method name as kubernetes
+	// for auth/kubernetes/login.
+	Path string `cfg:"path"`
+	// Role for kubernetes and jwt methods.
+	Role string `cfg:"role"`
+	// RoleID for approle method.
+	RoleID string `cfg:"role_id"`
+	// SecretID for approle method, SecretIDFile to read it from a file.
+	SecretID     string `cfg:"secret_id"`
+	SecretIDFile string `cfg:"secret_id_file"`
+	// JWT for kubernetes and jwt methods, JWTFile to read it from a file.
+	// Kubernetes default is the service account token file.
+	JWT     string `cfg:"jwt"`
+	JWTFile string `cfg:"jwt_file"`
+	// Token for token method, TokenFile to read it from a file.
+	// Default is VAULT_TOKEN env var.
+	Token     string `cfg:"token"`
+	TokenFile string `cfg:"token_file"`
 }
 
 type ConfigFile struct {
@@ -86,6 +261,11 @@ type ConfigFile struct {
 	Template bool `cfg:"template"`
 	// Base64 to decode the content.
 	Base64 bool `cfg:"base64"`
+	// Decrypt the content before processing.
+	Decrypt *ConfigDecrypt `cfg:"decrypt"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
 }
 
 type ConfigHTTP struct {
@@ -103,11 +283,16 @@ type ConfigHTTP struct {
 	Body string `cfg:"body"`
 	// Timeout for the request, default is no timeout.
 	Timeout time.Duration `cfg:"timeout"`
-	// Codec YAML,JSON,TOML default detects from the response Content-Type
+	// Codec YAML,JSON,TOML,DOTENV,PROPERTIES,INI,HCL default detects from the response Content-Type
 	// and falls back to YAML.
 	Codec string `cfg:"codec"`
 	// InsecureSkipVerify disables TLS certificate verification.
 	InsecureSkipVerify bool `cfg:"insecure_skip_verify"`
+	// Interval between polls when used as a dynamic source, default is 30s.
+	Interval time.Duration `cfg:"interval"`
+	// BackoffMax caps the exponential backoff after failed polls when used as
+	// a dynamic source, default is 5m.
+	BackoffMax time.Duration `cfg:"backoff_max"`
 	// Raw to load as raw, don't mix with other loaders.
 	Raw bool `cfg:"raw"`
 	// InnerPath is get the inner path from response, / separated as db/settings.
@@ -119,12 +304,31 @@ type ConfigHTTP struct {
 	Template bool `cfg:"template"`
 	// Base64 to decode the content.
 	Base64 bool `cfg:"base64"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
+}
+
+type ConfigEnv struct {
+	// Name for export, default is empty.
+	Name string `cfg:"name"`
+	// Prefix to select env variables, trimmed from the keys as APP_.
+	Prefix string `cfg:"prefix"`
+	// Separator for nested keys, default is __ as APP_DB__HOST for db/host.
+	Separator string `cfg:"separator"`
+	// KeepCase keeps the case of keys, default is lowercase.
+	KeepCase bool `cfg:"keep_case"`
+	// Map is the wrapper map, / separated as db/settings.
+	Map string `cfg:"map"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
 }
 
 type ConfigContent struct {
 	// Name for export, default is empty.
 	Name string `cfg:"name"`
-	// Codec YAML,JSON,TOML default is YAML.
+	// Codec YAML,JSON,TOML,DOTENV,PROPERTIES,INI,HCL default is YAML.
 	Codec   string `cfg:"codec"`
 	Content string `cfg:"content"`
 	Raw     bool   `cfg:"raw"`
@@ -137,4 +341,22 @@ type ConfigContent struct {
 	Template bool `cfg:"template"`
 	// Base64 to decode the content.
 	Base64 bool `cfg:"base64"`
+	// Decrypt the content before processing.
+	Decrypt *ConfigDecrypt `cfg:"decrypt"`
+	// Merge mode into previous sources: deep (default), replace, append, delete.
+	// Modes can be combined with comma as "append,delete".
+	Merge string `cfg:"merge"`
+}
+
+// ConfigDecrypt decrypts content with age identities.
+type ConfigDecrypt struct {
+	// Type is sops or age, default is sops.
+	//  - sops decrypts a SOPS YAML/JSON document and verifies the MAC.
+	//  - age decrypts a binary or armored age file.
+	Type string `cfg:"type"`
+	// IdentityFile is the age identity file.
+	IdentityFile string `cfg:"identity_file"`
+	// IdentityEnv is the env var holding the age identity.
+	// Default is SOPS_AGE_KEY or the file in SOPS_AGE_KEY_FILE.
+	IdentityEnv string `cfg:"identity_env"`
 }
@@ -0,0 +1,264 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	consulCatalogWaitTime    = 5 * time.Minute
+	consulCatalogBackoffBase = time.Second
+	consulCatalogBackoffMax  = time.Minute
+)
+
+// loadServices returns the healthy instances of the configured services keyed
+// by service name.
+func (c *consulClient) loadServices(ctx context.Context, cfg *ConfigConsulCatalog) (map[string]interface{}, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	v := make(map[string]interface{}, len(cfg.Services))
+	for _, service := range cfg.Services {
+		instances, _, err := c.loadService(ctx, cfg, service, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		v[service] = instances
+	}
+
+	return v, nil
+}
+
+// loadService returns the instances of service. A non zero index makes it a
+// blocking query returning after a change or the wait time.
+func (c *consulClient) loadService(ctx context.Context, cfg *ConfigConsulCatalog, service string, index uint64) ([]interface{}, uint64, error) {
+	opts := &api.QueryOptions{
+		Datacenter: cfg.Datacenter,
+		WaitIndex:  index,
+		WaitTime:   consulCatalogWaitTime,
+	}
+
+	entries, meta, err := c.client.Health().ServiceMultipleTags(service, cfg.Tags, !cfg.All, opts.WithContext(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get consul service %s: %w", service, err)
+	}
+
+	instances := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		instances = append(instances, serviceInstance(entry))
+	}
+
+	return instances, meta.LastIndex, nil
+}
+
+// serviceInstance converts a health entry to a template friendly map.
+func serviceInstance(entry *api.ServiceEntry) map[string]interface{} {
+	address := entry.Service.Address
+	if address == "" {
+		address = entry.Node.Address
+	}
+
+	tags := make([]interface{}, 0, len(entry.Service.Tags))
+	for _, tag := range entry.Service.Tags {
+		tags = append(tags, tag)
+	}
+
+	meta := make(map[string]interface{}, len(entry.Service.Meta))
+	for k, v := range entry.Service.Meta {
+		meta[k] = v
+	}
+
+	return map[string]interface{}{
+		"id":         entry.Service.ID,
+		"service":    entry.Service.Service,
+		"node":       entry.Node.Node,
+		"address":    address,
+		"port":       entry.Service.Port,
+		"tags":       tags,
+		"meta":       meta,
+		"datacenter": entry.Node.Datacenter,
+	}
+}
+
+// dynamicServices watches the services with blocking queries and returns a
+// channel with all services, starting with the current instances and followed
+// by every membership or health change.
+//
+// The returned channel is closed when ctx is cancelled.
+func (c *consulClient) dynamicServices(ctx context.Context, wg *sync.WaitGroup, cfg *ConfigConsulCatalog) (<-chan map[string]interface{}, func(), error) {
+	if err := c.connect(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	type update struct {
+		service   string
+		instances []interface{}
+	}
+
+	updates := make(chan update)
+	vChannel := make(chan map[string]interface{})
+
+	current := make(map[string]interface{}, len(cfg.Services))
+	indexes := make(map[string]uint64, len(cfg.Services))
+	for _, service := range cfg.Services {
+		instances, index, err := c.loadService(ctx, cfg, service, 0)
+		if err != nil {
+			cancel()
+
+			return nil, nil, err
+		}
+
+		current[service] = instances
+		// an index of 0 makes the query non blocking
+		indexes[service] = max(index, 1)
+	}
+
+	for _, service := range cfg.Services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			index := indexes[service]
+			failures := 0
+
+			for {
+				instances, newIndex, err := c.loadService(ctx, cfg, service, index)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+
+					wait := backoff(consulCatalogBackoffBase, consulCatalogBackoffMax, failures)
+					failures++
+
+					slog.Warn("failed to watch consul service", "service", service, "retry", wait.String(), "err", err.Error())
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(wait):
+					}
+
+					continue
+				}
+
+				failures = 0
+
+				// index went backwards, consul state was reset
+				newIndex = max(newIndex, 1)
+				reset := newIndex < index
+
+				if newIndex == index && !reset {
+					// wait before the next query, consul answers a stale
+					// index without blocking
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(consulCatalogBackoffBase):
+					}
+
+					continue
+				}
+
+				index = newIndex
+
+				select {
+				case <-ctx.Done():
+					return
+				case updates <- update{service: service, instances: instances}:
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(vChannel)
+
+		send := func() bool {
+			v := make(map[string]interface{}, len(current))
+			for k, instances := range current {
+				v[k] = instances
+			}
+
+			select {
+			case <-ctx.Done():
+				return false
+			case vChannel <- v:
+				return true
+			}
+		}
+
+		if !send() {
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case u := <-updates:
+				if reflect.DeepEqual(current[u.service], u.instances) {
+					continue
+				}
+
+				current[u.service] = u.instances
+
+				if !send() {
+					return
+				}
+			}
+		}
+	}()
+
+	return vChannel, cancel, nil
+}
+
+// process wraps the services with Map and merges them.
+func (c *ConfigConsulCatalog) process(to *Data, services map[string]interface{}) error {
+	v := MapPath(c.Map, services).(map[string]interface{})
+
+	if err := to.Merge(v, c.Merge); err != nil {
+		return err
+	}
+
+	to.AddHold(c.Name, services)
+
+	return nil
+}
+
+func (c ConfigStatic) loadConsulCatalog(ctx context.Context, to *Data, cl *clients) error {
+	services, err := cl.consul.loadServices(ctx, c.ConsulCatalog)
+	if err != nil {
+		return err
+	}
+
+	return c.ConsulCatalog.process(to, services)
+}
+
+func (c ConfigDynamic) loadConsulCatalog(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
+	ch, cancel, err := cl.consul.dynamicServices(ctx, wg, c.ConsulCatalog)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchDynamic(ctx, wg, ch, cancel, to, config, call, func(to *Data, services map[string]interface{}) error {
+		// the services are kept for the next rebuild, merge a copy
+		if err := c.ConsulCatalog.process(to, copyMap(services)); err != nil {
+			return fmt.Errorf("failed to load consul catalog: %w", err)
+		}
+
+		return nil
+	}), nil
+}
@@ -0,0 +1,280 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeConsulCatalog serves health/service with blocking queries.
+type fakeConsulCatalog struct {
+	mu      sync.Mutex
+	index   uint64
+	changed chan struct{}
+	entries map[string][]*api.ServiceEntry
+}
+
+func (f *fakeConsulCatalog) set(service string, entries ...*api.ServiceEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.index++
+	f.entries[service] = entries
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsulCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Path[len("/v1/health/service/"):]
+	waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	f.mu.Lock()
+	if waitIndex >= f.index {
+		changed := f.changed
+		f.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Second):
+		}
+
+		f.mu.Lock()
+	}
+
+	entries := f.entries[service]
+	index := f.index
+	f.mu.Unlock()
+
+	if entries == nil {
+		entries = []*api.ServiceEntry{}
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+func consulEntry(id, address string, port int) *api.ServiceEntry {
+	return &api.ServiceEntry{
+		Node:    &api.Node{Node: "node-1", Address: "10.0.0.1", Datacenter: "dc1"},
+		Service: &api.AgentService{ID: id, Service: "web", Address: address, Port: port, Tags: []string{"v1"}},
+	}
+}
+
+func TestConsulClient_DynamicServices(t *testing.T) {
+	fake := &fakeConsulCatalog{
+		index:   1,
+		changed: make(chan struct{}),
+		entries: map[string][]*api.ServiceEntry{},
+	}
+	fake.set("web", consulEntry("web-1", "", 8080))
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl := &clients{consul: &consulClient{client: client, kv: client.KV()}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	config := &Config{Name: "app"}
+	dynamic := ConfigDynamic{ConsulCatalog: &ConfigConsulCatalog{
+		Name:     "catalog",
+		Services: []string{"web"},
+		Map:      "upstreams",
+	}}
+
+	waitCtx, err := dynamic.load(ctx, wg, newDynamicData(Data{}, 1).source(0), cl, config, func(_ context.Context, _ string, data map[string]interface{}) {
+		calls <- data
+	})
+	if err != nil {
+		t.Fatalf("ConfigDynamic.load() error = %v", err)
+	}
+
+	<-waitCtx.Done()
+
+	wantInstances := func(want ...map[string]interface{}) {
+		t.Helper()
+
+		select {
+		case data := <-calls:
+			got := data["app"].(map[string]interface{})["upstreams"].(map[string]interface{})["web"]
+
+			wantList := make([]interface{}, 0, len(want))
+			for _, w := range want {
+				wantList = append(wantList, w)
+			}
+
+			if !reflect.DeepEqual(got, wantList) {
+				t.Fatalf("instances = %v, want %v", got, wantList)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no consul catalog call")
+		}
+	}
+
+	instance := func(id, address string, port int) map[string]interface{} {
+		return map[string]interface{}{
+			"id":         id,
+			"service":    "web",
+			"node":       "node-1",
+			"address":    address,
+			"port":       port,
+			"tags":       []interface{}{"v1"},
+			"meta":       map[string]interface{}{},
+			"datacenter": "dc1",
+		}
+	}
+
+	wantInstances(instance("web-1", "10.0.0.1", 8080))
+
+	fake.set("web", consulEntry("web-1", "", 8080), consulEntry("web-2", "10.0.0.2", 9090))
+
+	wantInstances(instance("web-1", "10.0.0.1", 8080), instance("web-2", "10.0.0.2", 9090))
+
+	fake.set("web")
+
+	wantInstances()
+}
+
+func TestConsulClient_DynamicServicesIndexZero(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	// consul answers without blocking for an index of 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		w.Header().Set("X-Consul-Index", "0")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]*api.ServiceEntry{consulEntry("web-1", "", 8080)})
+	}))
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &consulClient{client: client, kv: client.KV()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	ch, stop, err := c.dynamicServices(ctx, wg, &ConfigConsulCatalog{Services: []string{"web"}})
+	if err != nil {
+		t.Fatalf("consulClient.dynamicServices() error = %v", err)
+	}
+	defer stop()
+
+	<-ch
+
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls > 3 {
+		t.Errorf("consulClient.dynamicServices() queries = %d, want a backoff", calls)
+	}
+}
+
+func TestConfigDynamic_LoadConsulCatalogMerge(t *testing.T) {
+	fake := &fakeConsulCatalog{
+		index:   1,
+		changed: make(chan struct{}),
+		entries: map[string][]*api.ServiceEntry{},
+	}
+	fake.set("web", consulEntry("web-1", "", 8080))
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl := &clients{consul: &consulClient{client: client, kv: client.KV()}}
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "b.yaml"), []byte("b: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	exportPath := filepath.Join(tempDir, "out", "app.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	config := &Config{Name: "app", Export: exportPath}
+	state := newDynamicData(Data{}, 2)
+
+	for i, dynamic := range []ConfigDynamic{
+		{File: &ConfigFile{Path: filepath.Join(tempDir, "b.yaml")}},
+		{ConsulCatalog: &ConfigConsulCatalog{Services: []string{"web"}, Map: "upstreams"}},
+	} {
+		waitCtx, err := dynamic.load(ctx, wg, state.source(i), cl, config, func(_ context.Context, _ string, data map[string]interface{}) {
+			select {
+			case calls <- data:
+			default:
+			}
+		})
+		if err != nil {
+			t.Fatalf("ConfigDynamic.load() error = %v", err)
+		}
+
+		<-waitCtx.Done()
+	}
+
+	upstreams := func(ports ...int) map[string]interface{} {
+		instances := make([]interface{}, 0, len(ports))
+		for i, port := range ports {
+			instances = append(instances, serviceInstance(consulEntry("web-"+strconv.Itoa(i+1), "", port)))
+		}
+
+		return map[string]interface{}{"b": 0, "upstreams": map[string]interface{}{"web": instances}}
+	}
+
+	wantLoad(t, calls, exportPath, upstreams(8080))
+
+	// a membership change keeps the file values
+	fake.set("web", consulEntry("web-1", "", 8080), consulEntry("web-2", "", 9090))
+
+	wantLoad(t, calls, exportPath, upstreams(8080, 9090))
+}
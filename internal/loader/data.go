@@ -1,6 +1,11 @@
 package loader
 
-import "github.com/rakunlabs/mapx"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rakunlabs/mapx"
+)
 
 // Data holds the accumulated state while loading a single Config.
 //
@@ -26,13 +31,124 @@ func (d *Data) AddHold(k string, v interface{}) {
 	d.Hold[k] = v
 }
 
-// Merge merges v into the running Map.
-func (d *Data) Merge(v map[string]interface{}) {
+// Merge merges v into the running Map with the given merge mode.
+func (d *Data) Merge(v map[string]interface{}, mode string) error {
 	if d.Map == nil {
 		d.Map = map[string]interface{}{}
 	}
 
-	mapx.Merge(v, d.Map)
+	opt, err := parseMergeMode(mode)
+	if err != nil {
+		return err
+	}
+
+	if opt == (mergeOptions{}) {
+		mapx.Merge(v, d.Map)
+
+		return nil
+	}
+
+	mergeMap(v, d.Map, opt)
+
+	return nil
+}
+
+const (
+	// MergeDeep merges nested maps, other values are replaced. Default mode.
+	MergeDeep = "deep"
+	// MergeReplace replaces top level keys without merging nested maps.
+	MergeReplace = "replace"
+	// MergeAppend appends lists to the existing lists.
+	MergeAppend = "append"
+	// MergeDelete deletes existing keys when the new value is null.
+	MergeDelete = "delete"
+)
+
+type mergeOptions struct {
+	replace     bool
+	appendLists bool
+	deleteNull  bool
+}
+
+// parseMergeMode parses a comma separated merge mode as "append,delete".
+func parseMergeMode(mode string) (mergeOptions, error) {
+	var opt mergeOptions
+
+	deep := false
+	for _, m := range strings.Split(mode, ",") {
+		switch strings.ToLower(strings.TrimSpace(m)) {
+		case "":
+		case MergeDeep:
+			deep = true
+		case MergeReplace:
+			opt.replace = true
+		case MergeAppend, "append_lists", "append-lists":
+			opt.appendLists = true
+		case MergeDelete, "null_delete", "null-delete":
+			opt.deleteNull = true
+		default:
+			return opt, fmt.Errorf("unknown merge mode %q", m)
+		}
+	}
+
+	if deep && opt.replace {
+		return opt, fmt.Errorf("merge mode %q cannot be both deep and replace", mode)
+	}
+
+	return opt, nil
+}
+
+func mergeMap(src, dst map[string]interface{}, opt mergeOptions) {
+	for k, v := range src {
+		if v == nil && opt.deleteNull {
+			delete(dst, k)
+
+			continue
+		}
+
+		if !opt.replace {
+			if sm, ok := v.(map[string]interface{}); ok {
+				if dm, ok := dst[k].(map[string]interface{}); ok {
+					mergeMap(sm, dm, opt)
+
+					continue
+				}
+			}
+		}
+
+		if opt.appendLists {
+			if sl, ok := v.([]interface{}); ok {
+				if dl, ok := dst[k].([]interface{}); ok {
+					dst[k] = append(append(make([]interface{}, 0, len(dl)+len(sl)), dl...), sl...)
+
+					continue
+				}
+			}
+		}
+
+		if sm, ok := v.(map[string]interface{}); ok && opt.deleteNull {
+			v = dropNull(sm)
+		}
+
+		dst[k] = v
+	}
+}
+
+// dropNull returns a copy of m without null values.
+func dropNull(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch vv := v.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			out[k] = dropNull(vv)
+		default:
+			out[k] = v
+		}
+	}
+
+	return out
 }
 
 func copyMap(in map[string]interface{}) map[string]interface{} {
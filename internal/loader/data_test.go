@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestData_Merge(t *testing.T) {
+	base := func() map[string]interface{} {
+		return map[string]interface{}{
+			"db": map[string]interface{}{
+				"host": "localhost",
+				"port": 5432,
+			},
+			"hosts": []interface{}{"a"},
+			"debug": true,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		v       map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "deep",
+			v: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "db"},
+				"hosts": []interface{}{"b"},
+			},
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "db", "port": 5432},
+				"hosts": []interface{}{"b"},
+				"debug": true,
+			},
+		},
+		{
+			name: "replace",
+			mode: "replace",
+			v: map[string]interface{}{
+				"db": map[string]interface{}{"host": "db"},
+			},
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "db"},
+				"hosts": []interface{}{"a"},
+				"debug": true,
+			},
+		},
+		{
+			name: "append",
+			mode: "append",
+			v: map[string]interface{}{
+				"hosts": []interface{}{"b"},
+			},
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "localhost", "port": 5432},
+				"hosts": []interface{}{"a", "b"},
+				"debug": true,
+			},
+		},
+		{
+			name: "delete",
+			mode: "append, delete",
+			v: map[string]interface{}{
+				"db":    map[string]interface{}{"port": nil},
+				"debug": nil,
+				"new":   map[string]interface{}{"x": nil, "y": 1},
+			},
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "localhost"},
+				"hosts": []interface{}{"a"},
+				"new":   map[string]interface{}{"y": 1},
+			},
+		},
+		{
+			name:    "unknown",
+			mode:    "shallow",
+			wantErr: true,
+		},
+		{
+			name:    "deep and replace",
+			mode:    "deep,replace",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{Map: base()}
+			err := d.Merge(tt.v, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Data.Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(d.Map, tt.want) {
+				t.Errorf("Data.Merge() = %v, want %v", d.Map, tt.want)
+			}
+		})
+	}
+}
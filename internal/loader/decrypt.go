@@ -0,0 +1,188 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	sopsaes "github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	sopsconfig "github.com/getsops/sops/v3/config"
+	"github.com/getsops/sops/v3/keyservice"
+	"google.golang.org/grpc"
+)
+
+const (
+	envSOPSAgeKey     = "SOPS_AGE_KEY"
+	envSOPSAgeKeyFile = "SOPS_AGE_KEY_FILE"
+)
+
+// decrypt decrypts data with the configured type before any other processing.
+func (c *ConfigDecrypt) decrypt(data []byte) ([]byte, error) {
+	identities, err := c.identities()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(c.Type) {
+	case "", "sops":
+		return decryptSOPS(data, identities)
+	case "age":
+		return decryptAge(data, identities)
+	default:
+		return nil, fmt.Errorf("decrypt type %s not found", c.Type)
+	}
+}
+
+// identities reads the age identities from IdentityFile or IdentityEnv,
+// falling back to the SOPS_AGE_KEY and SOPS_AGE_KEY_FILE env vars. It returns
+// nil when none is set.
+func (c *ConfigDecrypt) identities() ([]age.Identity, error) {
+	var keys string
+
+	switch {
+	case c.IdentityFile != "":
+		b, err := os.ReadFile(c.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file %s: %w", c.IdentityFile, err)
+		}
+
+		keys = string(b)
+	case c.IdentityEnv != "":
+		keys = os.Getenv(c.IdentityEnv)
+		if keys == "" {
+			return nil, fmt.Errorf("identity env %s is empty", c.IdentityEnv)
+		}
+	case os.Getenv(envSOPSAgeKey) != "":
+		keys = os.Getenv(envSOPSAgeKey)
+	case os.Getenv(envSOPSAgeKeyFile) != "":
+		b, err := os.ReadFile(os.Getenv(envSOPSAgeKeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file %s: %w", os.Getenv(envSOPSAgeKeyFile), err)
+		}
+
+		keys = string(b)
+	default:
+		return nil, nil
+	}
+
+	identities, err := age.ParseIdentities(strings.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// decryptAge decrypts a binary or armored age file.
+func decryptAge(data []byte, identities []age.Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("no age identity given, set identity_file or identity_env")
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		r = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt age content: %w", err)
+	}
+
+	v, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read age content: %w", err)
+	}
+
+	return v, nil
+}
+
+// decryptSOPS decrypts a SOPS YAML or JSON document with the sops library and
+// verifies the MAC. The result keeps the input format.
+//
+// Age keys are decrypted with the given identities, without them and for the
+// other key types the local sops key service is used like the sops CLI.
+func decryptSOPS(data []byte, identities []age.Identity) ([]byte, error) {
+	format := formats.Yaml
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		format = formats.Json
+	}
+
+	store := common.StoreForFormat(format, sopsconfig.NewStoresConfig())
+
+	tree, err := store.LoadEncryptedFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sops content: %w", err)
+	}
+
+	key, err := tree.Metadata.GetDataKeyWithKeyServices([]keyservice.KeyServiceClient{
+		ageKeyService{
+			KeyServiceClient: keyservice.NewLocalClient(),
+			identities:       identities,
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sops data key: %w", err)
+	}
+
+	cipher := sopsaes.NewCipher()
+
+	mac, err := tree.Decrypt(key, cipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sops content: %w", err)
+	}
+
+	originalMAC, err := cipher.Decrypt(tree.Metadata.MessageAuthenticationCode, key, tree.Metadata.LastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sops mac: %w", err)
+	}
+
+	if originalMAC != mac {
+		return nil, errors.New("failed to verify sops data integrity, mac mismatch")
+	}
+
+	v, err := store.EmitPlainFile(tree.Branches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode decrypted sops content: %w", err)
+	}
+
+	return v, nil
+}
+
+// ageKeyService decrypts the age keys of a sops document with the configured
+// identities instead of the sops env vars.
+type ageKeyService struct {
+	keyservice.KeyServiceClient
+	identities []age.Identity
+}
+
+func (s ageKeyService) Decrypt(ctx context.Context, req *keyservice.DecryptRequest, opts ...grpc.CallOption) (*keyservice.DecryptResponse, error) {
+	ageKey := req.GetKey().GetAgeKey()
+	if ageKey == nil || len(s.identities) == 0 {
+		return s.KeyServiceClient.Decrypt(ctx, req, opts...)
+	}
+
+	key := &sopsage.MasterKey{
+		Recipient:    ageKey.GetRecipient(),
+		EncryptedKey: string(req.GetCiphertext()),
+	}
+
+	sopsage.ParsedIdentities(s.identities).ApplyToMasterKey(key)
+
+	plaintext, err := key.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyservice.DecryptResponse{Plaintext: plaintext}, nil
+}
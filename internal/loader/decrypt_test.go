@@ -0,0 +1,200 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func TestConfigs_LoadDecrypt(t *testing.T) {
+	tempDir := t.TempDir()
+
+	identityFile := filepath.Join("testdata", "sops", "age.key")
+
+	identities, err := (&ConfigDecrypt{IdentityFile: identityFile}).identities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// armored age blob for the identity in testdata
+	ageBuf := &bytes.Buffer{}
+	armorWriter := armor.NewWriter(ageBuf)
+
+	ageWriter, err := age.Encrypt(armorWriter, identities[0].(*age.X25519Identity).Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = ageWriter.Write([]byte("my-secret-token"))
+	_ = ageWriter.Close()
+	_ = armorWriter.Close()
+
+	tamper := func(name, old, new string) string {
+		t.Helper()
+
+		v, err := os.ReadFile(filepath.Join("testdata", "sops", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Contains(v, []byte(old)) {
+			t.Fatalf("%s has no %q", name, old)
+		}
+
+		file := filepath.Join(tempDir, "tampered-"+name)
+		if err := os.WriteFile(file, bytes.Replace(v, []byte(old), []byte(new), 1), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return file
+	}
+
+	// tampered value of an unencrypted leaf must fail the MAC check
+	tamperedFile := tamper("app.enc.yaml", "host: localhost", "host: evil")
+	tamperedSuffixFile := tamper("suffix.enc.yaml", "host_unencrypted: localhost", "host_unencrypted: evil")
+	// with mac_only_encrypted the plain values are not authenticated
+	tamperedRegexFile := tamper("regex.enc.yaml", "url: https://example.com", "url: https://example.org")
+
+	t.Setenv("TEST_AGE_KEY", identities[0].(*age.X25519Identity).String())
+
+	tests := []struct {
+		name    string
+		static  ConfigStatic
+		export  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "sops yaml",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:      filepath.Join("testdata", "sops", "app.enc.yaml"),
+					InnerPath: "db",
+					Decrypt:   &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			export: "db.json",
+			want:   "{\n  \"host\": \"localhost\",\n  \"password\": \"s3cr3t\",\n  \"port\": 5432,\n  \"ratio\": 1.5,\n  \"tags\": [\n    \"primary\",\n    \"eu\"\n  ]\n}\n",
+		},
+		{
+			name: "sops json with identity env",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:    filepath.Join("testdata", "sops", "app.enc.json"),
+					Decrypt: &ConfigDecrypt{Type: "sops", IdentityEnv: "TEST_AGE_KEY"},
+				},
+			},
+			export: "app.json",
+			want:   "{\n  \"db\": {\n    \"password\": \"s3cr3t\",\n    \"port\": 5432,\n    \"user\": \"admin\"\n  }\n}\n",
+		},
+		{
+			name: "sops encrypted_regex with mac_only_encrypted",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:      filepath.Join("testdata", "sops", "regex.enc.yaml"),
+					InnerPath: "api",
+					Decrypt:   &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			export: "api.json",
+			want:   "{\n  \"token\": \"abc123\",\n  \"url\": \"https://example.com\"\n}\n",
+		},
+		{
+			name: "sops mac_only_encrypted ignores plain values",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:      tamperedRegexFile,
+					InnerPath: "api",
+					Decrypt:   &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			export: "api-tampered.json",
+			want:   "{\n  \"token\": \"abc123\",\n  \"url\": \"https://example.org\"\n}\n",
+		},
+		{
+			name: "sops unencrypted_suffix",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:      filepath.Join("testdata", "sops", "suffix.enc.yaml"),
+					InnerPath: "db",
+					Decrypt:   &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			export: "suffix.json",
+			want:   "{\n  \"host_unencrypted\": \"localhost\",\n  \"password\": \"s3cr3t\",\n  \"port\": 5432\n}\n",
+		},
+		{
+			name: "sops unencrypted_suffix mac mismatch",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:    tamperedSuffixFile,
+					Decrypt: &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "age content",
+			static: ConfigStatic{
+				Content: &ConfigContent{
+					Content: ageBuf.String(),
+					Raw:     true,
+					Decrypt: &ConfigDecrypt{Type: "age", IdentityFile: identityFile},
+				},
+			},
+			export: "token",
+			want:   "my-secret-token",
+		},
+		{
+			name: "sops mac mismatch",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:    tamperedFile,
+					Decrypt: &ConfigDecrypt{IdentityFile: identityFile},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing identity env",
+			static: ConfigStatic{
+				File: &ConfigFile{
+					Path:    filepath.Join("testdata", "sops", "app.enc.yaml"),
+					Decrypt: &ConfigDecrypt{IdentityEnv: "TEST_AGE_KEY_MISSING"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Configs{{Statics: []ConfigStatic{tt.static}}}
+			if tt.export != "" {
+				c[0].Export = filepath.Join(tempDir, tt.export)
+			}
+
+			if err := c.Load(context.Background(), nil, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("Configs.Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			v, err := os.ReadFile(c[0].Export)
+			if err != nil {
+				t.Fatalf("Configs.Load() read export error = %v", err)
+			}
+
+			if string(v) != tt.want {
+				t.Errorf("Configs.Load() content = \n%q\n, want \n%q\n", string(v), tt.want)
+			}
+		})
+	}
+}
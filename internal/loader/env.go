@@ -0,0 +1,114 @@
+package loader
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultEnvSeparator = "__"
+
+// loadEnv builds a nested map from the process environment.
+//
+// Variables are filtered and trimmed by Prefix, split into nested keys by
+// Separator and lowercased, so APP_DB__HOST becomes db.host with the APP_
+// prefix. Values are weakly typed to bool, int and float when they parse.
+func (c *ConfigEnv) loadEnv(environ []string) map[string]interface{} {
+	separator := c.Separator
+	if separator == "" {
+		separator = defaultEnvSeparator
+	}
+
+	// sorted so a key set both as value and as parent resolves the same way
+	sort.Strings(environ)
+
+	v := map[string]interface{}{}
+
+	for _, e := range environ {
+		key, value, ok := strings.Cut(e, "=")
+		if !ok || !strings.HasPrefix(key, c.Prefix) {
+			continue
+		}
+
+		key = strings.TrimPrefix(key, c.Prefix)
+		if key == "" {
+			continue
+		}
+
+		if !c.KeepCase {
+			key = strings.ToLower(key)
+		}
+
+		setPath(v, strings.Split(key, separator), weakValue(value))
+	}
+
+	return v
+}
+
+// setPath sets value at the nested keys of m. A parent map wins over a
+// plain value with the same key.
+func setPath(m map[string]interface{}, keys []string, value interface{}) {
+	for i, k := range keys {
+		if k == "" {
+			return
+		}
+
+		if i == len(keys)-1 {
+			if _, ok := m[k].(map[string]interface{}); !ok {
+				m[k] = value
+			}
+
+			return
+		}
+
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+
+		m = next
+	}
+}
+
+// weakValue converts a string to bool, int or float when it parses as one.
+// Numbers with leading zeros stay strings to keep values like zip codes.
+func weakValue(v string) interface{} {
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	trimmed := strings.TrimPrefix(v, "-")
+	if len(trimmed) > 1 && trimmed[0] == '0' && trimmed[1] != '.' {
+		return v
+	}
+
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !strings.ContainsAny(v, "xXnN") {
+		return f
+	}
+
+	return v
+}
+
+func (c ConfigStatic) loadEnv(to *Data) error {
+	vMap := c.Env.loadEnv(os.Environ())
+
+	innerValue := MapPath(c.Env.Map, vMap)
+	if m, ok := innerValue.(map[string]interface{}); ok {
+		if err := to.Merge(m, c.Env.Merge); err != nil {
+			return err
+		}
+	}
+
+	to.AddHold(c.Env.Name, innerValue)
+
+	return nil
+}
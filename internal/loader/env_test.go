@@ -0,0 +1,73 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigEnv_loadEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConfigEnv
+		environ []string
+		want    map[string]interface{}
+	}{
+		{
+			name:   "prefix and separator",
+			config: ConfigEnv{Prefix: "APP_"},
+			environ: []string{
+				"APP_DB__HOST=localhost",
+				"APP_DB__PORT=5432",
+				"APP_DEBUG=true",
+				"APP_RATIO=0.5",
+				"APP_ZIP=01234",
+				"APP_URL=http://a=b",
+				"OTHER=skip",
+			},
+			want: map[string]interface{}{
+				"db": map[string]interface{}{
+					"host": "localhost",
+					"port": int64(5432),
+				},
+				"debug": true,
+				"ratio": 0.5,
+				"zip":   "01234",
+				"url":   "http://a=b",
+			},
+		},
+		{
+			name:   "custom separator keep case",
+			config: ConfigEnv{Prefix: "X.", Separator: ".", KeepCase: true},
+			environ: []string{
+				"X.Server.Name=web",
+				"X.Server.Inf=inf",
+			},
+			want: map[string]interface{}{
+				"Server": map[string]interface{}{
+					"Name": "web",
+					"Inf":  "inf",
+				},
+			},
+		},
+		{
+			name:   "parent map wins",
+			config: ConfigEnv{Prefix: "APP_"},
+			environ: []string{
+				"APP_DB__HOST=localhost",
+				"APP_DB=plain",
+			},
+			want: map[string]interface{}{
+				"db": map[string]interface{}{
+					"host": "localhost",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.loadEnv(tt.environ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfigEnv.loadEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
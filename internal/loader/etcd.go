@@ -0,0 +1,409 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultEtcdEndpoint    = "localhost:2379"
+	defaultEtcdDialTimeout = 5 * time.Second
+	etcdBackoffBase        = time.Second
+	etcdBackoffMax         = time.Minute
+)
+
+// etcdClients shares etcd clients between sources with the same connection.
+// A client is closed when the last source using it releases it.
+type etcdClients struct {
+	mutex   sync.Mutex
+	clients map[string]*etcdClient
+}
+
+type etcdClient struct {
+	client *clientv3.Client
+	refs   int
+}
+
+// get returns the client of the source, connecting on first use. Call release
+// when the source does not use the client anymore.
+func (c *etcdClients) get(cfg *ConfigEtcd) (*clientv3.Client, func(), error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	endpoints := cfg.endpoints()
+
+	key := fmt.Sprintf("%v|%s|%+v", endpoints, cfg.Username, cfg.TLS)
+	if v, ok := c.clients[key]; ok {
+		v.refs++
+
+		return v.client, c.release(key), nil
+	}
+
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultEtcdDialTimeout
+	}
+
+	config := clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		Logger:      zap.NewNop(),
+	}
+
+	if cfg.TLS != nil {
+		tlsInfo := transport.TLSInfo{
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			TrustedCAFile:      cfg.TLS.CAFile,
+			ServerName:         cfg.TLS.ServerName,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		}
+
+		tlsConfig, err := tlsInfo.ClientConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create etcd tls config: %w", err)
+		}
+
+		config.TLS = tlsConfig
+	}
+
+	client, err := clientv3.New(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+
+	if c.clients == nil {
+		c.clients = map[string]*etcdClient{}
+	}
+
+	c.clients[key] = &etcdClient{client: client, refs: 1}
+
+	return client, c.release(key), nil
+}
+
+// release returns a func dropping a reference of the client, the last one
+// closes it.
+func (c *etcdClients) release(key string) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+
+			v := c.clients[key]
+
+			v.refs--
+			if v.refs > 0 {
+				return
+			}
+
+			delete(c.clients, key)
+
+			if err := v.client.Close(); err != nil {
+				slog.Warn("failed to close etcd client", "err", err.Error())
+			}
+		})
+	}
+}
+
+// endpoints returns the configured endpoints, ETCD_ENDPOINTS or localhost.
+func (c *ConfigEtcd) endpoints() []string {
+	if len(c.Endpoints) > 0 {
+		return c.Endpoints
+	}
+
+	if v := os.Getenv("ETCD_ENDPOINTS"); v != "" {
+		return strings.Split(v, ",")
+	}
+
+	return []string{defaultEtcdEndpoint}
+}
+
+// codec returns the codec of the value, prefix reads are assembled as JSON.
+func (c *ConfigEtcd) codec() string {
+	if c.Prefix {
+		return "JSON"
+	}
+
+	return c.Codec
+}
+
+// key returns the key to read, a prefix ends with / so sibling keys like
+// app2 are not matched by app.
+func (c *ConfigEtcd) key() string {
+	if c.Prefix && c.Path != "" && !strings.HasSuffix(c.Path, "/") {
+		return c.Path + "/"
+	}
+
+	return c.Path
+}
+
+// loadEtcd reads the key or, with Prefix, every key under Path assembled in a
+// nested map split by /. It returns the revision of the read.
+func loadEtcd(ctx context.Context, client *clientv3.Client, cfg *ConfigEtcd) ([]byte, int64, error) {
+	var opts []clientv3.OpOption
+	if cfg.Prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+
+	resp, err := client.Get(ctx, cfg.key(), opts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get etcd key %s: %w", cfg.Path, err)
+	}
+
+	if !cfg.Prefix {
+		if len(resp.Kvs) == 0 {
+			return nil, resp.Header.Revision, nil
+		}
+
+		return resp.Kvs[0].Value, resp.Header.Revision, nil
+	}
+
+	v := map[string]interface{}{}
+	for _, kv := range resp.Kvs {
+		key := strings.Trim(strings.TrimPrefix(string(kv.Key), cfg.key()), "/")
+		if key == "" {
+			continue
+		}
+
+		setPath(v, strings.Split(key, "/"), string(kv.Value))
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to assemble etcd prefix %s: %w", cfg.Path, err)
+	}
+
+	return data, resp.Header.Revision, nil
+}
+
+// dynamicEtcd watches the key or prefix and returns a channel with the value,
+// starting with the current value and followed by every change.
+//
+// The value is read again after watch events, so deleted keys under a prefix
+// are reflected. A broken watch, as after a compaction, is restarted from a
+// fresh read with backoff.
+//
+// The returned channel is closed when ctx is cancelled.
+func dynamicEtcd(ctx context.Context, wg *sync.WaitGroup, client *clientv3.Client, cfg *ConfigEtcd) (<-chan []byte, func(), error) {
+	last, revision, err := loadEtcd(ctx, client, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	vChannel := make(chan []byte)
+
+	send := func(v []byte) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case vChannel <- v:
+			return true
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(vChannel)
+
+		if !send(last) {
+			return
+		}
+
+		failures := 0
+
+		for {
+			opts := []clientv3.OpOption{clientv3.WithRev(revision + 1)}
+			if cfg.Prefix {
+				opts = append(opts, clientv3.WithPrefix())
+			}
+
+			watchCtx, watchCancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+
+			for resp := range client.Watch(watchCtx, cfg.key(), opts...) {
+				if err := resp.Err(); err != nil {
+					slog.Warn("etcd watch error", "path", cfg.Path, "err", err.Error())
+
+					break
+				}
+
+				failures = 0
+
+				data, rev, err := loadEtcd(ctx, client, cfg)
+				if err != nil {
+					slog.Warn("failed to read etcd after watch event", "path", cfg.Path, "err", err.Error())
+
+					break
+				}
+
+				revision = rev
+
+				if bytes.Equal(data, last) {
+					continue
+				}
+
+				last = data
+
+				if !send(data) {
+					watchCancel()
+
+					return
+				}
+			}
+
+			watchCancel()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			wait := backoff(etcdBackoffBase, etcdBackoffMax, failures)
+			failures++
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			// read again, the watched revision may be compacted
+			data, rev, err := loadEtcd(ctx, client, cfg)
+			if err != nil {
+				slog.Warn("failed to read etcd", "path", cfg.Path, "retry", wait.String(), "err", err.Error())
+
+				continue
+			}
+
+			revision = rev
+
+			if !bytes.Equal(data, last) {
+				last = data
+
+				if !send(data) {
+					return
+				}
+			}
+		}
+	}()
+
+	return vChannel, cancel, nil
+}
+
+// process runs the etcd pipeline over the value data. It is shared by static
+// loads and dynamic watches.
+func (c *ConfigEtcd) process(to *Data, data []byte) error {
+	var err error
+
+	if c.Template {
+		v, err := renderTemplate(string(data), to.Hold)
+		if err != nil {
+			return err
+		}
+
+		data = v
+	}
+
+	var dataProcessed interface{}
+
+	if c.Raw {
+		if c.Map != "" {
+			vMap := MapPath(c.Map, data).(map[string]interface{})
+			if err := to.Merge(vMap, c.Merge); err != nil {
+				return err
+			}
+			dataProcessed = vMap
+		} else {
+			to.Raw = data
+			dataProcessed = data
+		}
+	} else {
+		var vMap map[string]interface{}
+		if err := decodeContent(c.codec(), data, &vMap); err != nil {
+			return err
+		}
+
+		innerValue := MapPath(c.Map, InnerPath(c.InnerPath, vMap))
+		if m, ok := innerValue.(map[string]interface{}); ok {
+			if err := to.Merge(m, c.Merge); err != nil {
+				return err
+			}
+			dataProcessed = innerValue
+		} else {
+			to.Raw = []byte(fmt.Sprint(innerValue))
+			dataProcessed = to.Raw
+		}
+	}
+
+	if c.Base64 && to.Raw != nil {
+		if to.Raw, err = base64.StdEncoding.DecodeString(string(to.Raw)); err != nil {
+			return fmt.Errorf("etcd decode base64 error: %w", err)
+		}
+
+		dataProcessed = to.Raw
+	}
+
+	to.AddHold(c.Name, dataProcessed)
+
+	return nil
+}
+
+func (c ConfigStatic) loadEtcd(ctx context.Context, to *Data, cl *clients) error {
+	client, release, err := cl.etcd.get(c.Etcd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	data, _, err := loadEtcd(ctx, client, c.Etcd)
+	if err != nil {
+		return err
+	}
+
+	return c.Etcd.process(to, data)
+}
+
+func (c ConfigDynamic) loadEtcd(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
+	client, release, err := cl.etcd.get(c.Etcd)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, cancel, err := dynamicEtcd(ctx, wg, client, c.Etcd)
+	if err != nil {
+		release()
+
+		return nil, err
+	}
+
+	// the client is closed when the watch is done
+	stop := func() {
+		cancel()
+		release()
+	}
+
+	return watchDynamic(ctx, wg, ch, stop, to, config, call, func(to *Data, data []byte) error {
+		if err := c.Etcd.process(to, data); err != nil {
+			return fmt.Errorf("failed to load etcd data: %w", err)
+		}
+
+		return nil
+	}), nil
+}
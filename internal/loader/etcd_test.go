@@ -0,0 +1,224 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// etcdEndpoint returns the etcd to test, tests are skipped when
+// ETCD_ENDPOINTS is not set.
+func etcdEndpoint(t *testing.T) string {
+	t.Helper()
+
+	endpoints := os.Getenv("ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("ETCD_ENDPOINTS not set")
+	}
+
+	return strings.Split(endpoints, ",")[0]
+}
+
+func TestConfigEtcd_key(t *testing.T) {
+	tests := []struct {
+		config ConfigEtcd
+		want   string
+	}{
+		{config: ConfigEtcd{Path: "app/config"}, want: "app/config"},
+		{config: ConfigEtcd{Path: "app", Prefix: true}, want: "app/"},
+		{config: ConfigEtcd{Path: "app/", Prefix: true}, want: "app/"},
+		{config: ConfigEtcd{Prefix: true}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.config.key(); got != tt.want {
+			t.Errorf("ConfigEtcd.key() %q = %q, want %q", tt.config.Path, got, tt.want)
+		}
+	}
+}
+
+func TestEtcdClients_get(t *testing.T) {
+	c := &etcdClients{}
+	cfg := &ConfigEtcd{Endpoints: []string{"127.0.0.1:1"}}
+
+	client, release, err := c.get(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, releaseShared, err := c.get(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shared != client {
+		t.Fatal("etcdClients.get() did not share the client")
+	}
+
+	release()
+	release()
+
+	if err := client.Ctx().Err(); err != nil {
+		t.Fatalf("etcdClients.get() closed a used client, err = %v", err)
+	}
+
+	releaseShared()
+
+	if client.Ctx().Err() == nil {
+		t.Error("etcdClients.get() kept the client after the last release")
+	}
+
+	if len(c.clients) != 0 {
+		t.Errorf("etcdClients.get() clients = %v, want none", c.clients)
+	}
+}
+
+func TestConfigs_LoadEtcd(t *testing.T) {
+	endpoint := etcdEndpoint(t)
+
+	cl := &clients{etcd: &etcdClients{}}
+	client, release, err := cl.etcd.get(&ConfigEtcd{Endpoints: []string{endpoint}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	put := func(key, value string) {
+		t.Helper()
+
+		if _, err := client.Put(ctx, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("app/config", "server:\n  port: 8080\n")
+	put("app/flat/db/host", "localhost")
+	put("app/flat/db/port", "5432")
+	put("app/flat/name", "turna")
+	// siblings sharing the prefix bytes are not under app/flat
+	put("app/flat2/name", "sibling")
+	put("app/flatten", "sibling")
+
+	t.Run("static", func(t *testing.T) {
+		to := &Data{}
+
+		for _, static := range []ConfigStatic{
+			{Etcd: &ConfigEtcd{Endpoints: []string{endpoint}, Path: "app/config", InnerPath: "server"}},
+			{Etcd: &ConfigEtcd{Endpoints: []string{endpoint}, Path: "app/flat", Prefix: true, Map: "flat"}},
+		} {
+			if err := static.load(ctx, to, cl); err != nil {
+				t.Fatalf("ConfigStatic.load() error = %v", err)
+			}
+		}
+
+		want := map[string]interface{}{
+			"port": 8080,
+			"flat": map[string]interface{}{
+				"db":   map[string]interface{}{"host": "localhost", "port": "5432"},
+				"name": "turna",
+			},
+		}
+
+		if !reflect.DeepEqual(to.Map, want) {
+			t.Errorf("ConfigStatic.load() = %v, want %v", to.Map, want)
+		}
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		calls := make(chan map[string]interface{}, 10)
+
+		config := &Config{Name: "app"}
+		dynamic := ConfigDynamic{Etcd: &ConfigEtcd{Endpoints: []string{endpoint}, Path: "app/flat/", Prefix: true}}
+
+		waitCtx, err := dynamic.load(ctx, wg, newDynamicData(Data{}, 1).source(0), cl, config, func(_ context.Context, _ string, data map[string]interface{}) {
+			calls <- data
+		})
+		if err != nil {
+			t.Fatalf("ConfigDynamic.load() error = %v", err)
+		}
+
+		<-waitCtx.Done()
+
+		wantCall := func(want map[string]interface{}) {
+			t.Helper()
+
+			select {
+			case data := <-calls:
+				if got := data["app"]; !reflect.DeepEqual(got, want) {
+					t.Fatalf("ConfigDynamic.load() = %v, want %v", got, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no etcd call")
+			}
+		}
+
+		wantCall(map[string]interface{}{
+			"db":   map[string]interface{}{"host": "localhost", "port": "5432"},
+			"name": "turna",
+		})
+
+		put("app/flat/db/host", "db.local")
+
+		wantCall(map[string]interface{}{
+			"db":   map[string]interface{}{"host": "db.local", "port": "5432"},
+			"name": "turna",
+		})
+
+		if _, err := client.Delete(ctx, "app/flat/name"); err != nil {
+			t.Fatal(err)
+		}
+
+		wantCall(map[string]interface{}{
+			"db": map[string]interface{}{"host": "db.local", "port": "5432"},
+		})
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		tempDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tempDir, "b.yaml"), []byte("b: 0\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		exportPath := filepath.Join(tempDir, "out", "app.json")
+
+		calls := make(chan map[string]interface{}, 10)
+
+		config := &Config{Name: "app", Export: exportPath}
+		state := newDynamicData(Data{}, 2)
+
+		for i, dynamic := range []ConfigDynamic{
+			{File: &ConfigFile{Path: filepath.Join(tempDir, "b.yaml")}},
+			{Etcd: &ConfigEtcd{Endpoints: []string{endpoint}, Path: "app/config"}},
+		} {
+			waitCtx, err := dynamic.load(ctx, wg, state.source(i), cl, config, func(_ context.Context, _ string, data map[string]interface{}) {
+				select {
+				case calls <- data:
+				default:
+				}
+			})
+			if err != nil {
+				t.Fatalf("ConfigDynamic.load() error = %v", err)
+			}
+
+			<-waitCtx.Done()
+		}
+
+		wantLoad(t, calls, exportPath, map[string]interface{}{"b": 0, "server": map[string]interface{}{"port": 8080}})
+
+		// a changed key keeps the file values
+		put("app/config", "server:\n  port: 9090\n")
+
+		wantLoad(t, calls, exportPath, map[string]interface{}{"b": 0, "server": map[string]interface{}{"port": 9090}})
+	})
+}
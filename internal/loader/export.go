@@ -0,0 +1,174 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rakunlabs/turna/pkg/runner"
+)
+
+const defaultHookTimeout = 30 * time.Second
+
+// exports returns the export targets, the Export path first.
+func (c *Config) exports() []ConfigExport {
+	targets := make([]ConfigExport, 0, len(c.Exports)+1)
+
+	if c.Export != "" {
+		targets = append(targets, ConfigExport{
+			Path:       c.Export,
+			FilePerm:   c.FilePerm,
+			FolderPerm: c.FolderPerm,
+		})
+	}
+
+	for _, e := range c.Exports {
+		if e.FilePerm == "" {
+			e.FilePerm = c.FilePerm
+		}
+
+		if e.FolderPerm == "" {
+			e.FolderPerm = c.FolderPerm
+		}
+
+		targets = append(targets, e)
+	}
+
+	return targets
+}
+
+// export writes the loaded data to every export target and runs the hook of
+// the targets whose content changed.
+func (c *Config) export(ctx context.Context, to *Data) error {
+	var errs []error
+
+	for _, e := range c.exports() {
+		if err := e.export(ctx, to); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *ConfigExport) export(ctx context.Context, to *Data) error {
+	data, err := e.render(to)
+	if err != nil {
+		return fmt.Errorf("export %s: %w", e.Path, err)
+	}
+
+	changed, err := writeAtomic(e.Path, data, e.FilePerm, e.FolderPerm)
+	if err != nil {
+		return fmt.Errorf("export %s: %w", e.Path, err)
+	}
+
+	if !changed || e.Hook == nil {
+		return nil
+	}
+
+	if err := e.Hook.run(ctx); err != nil {
+		return fmt.Errorf("export %s hook: %w", e.Path, err)
+	}
+
+	return nil
+}
+
+// render returns the content of the export; the template output, raw content
+// as is or the map encoded by the codec.
+func (e *ConfigExport) render(to *Data) ([]byte, error) {
+	if e.Template != "" {
+		return renderTemplate(e.Template, to.Hold)
+	}
+
+	if to.Raw != nil {
+		return to.Raw, nil
+	}
+
+	var (
+		c   codec
+		err error
+	)
+
+	if e.Codec != "" {
+		c, err = codecByName(e.Codec)
+	} else {
+		c, err = codecByExt(filepath.Ext(e.Path))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, to.Map); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// run runs the hook command and notifies the service.
+func (h *ConfigExportHook) run(ctx context.Context) error {
+	if len(h.Command) > 0 {
+		timeout := h.Timeout
+		if timeout == 0 {
+			timeout = defaultHookTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		out, err := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("command %v: %w: %s", h.Command, err, strings.TrimSpace(string(out)))
+		}
+
+		slog.Info("export hook command done", "command", h.Command)
+	}
+
+	if h.Service == "" {
+		return nil
+	}
+
+	if !h.Restart && h.Signal == "" {
+		return fmt.Errorf("service %s requires signal or restart", h.Service)
+	}
+
+	var command *runner.Command
+	if runner.GlobalReg != nil {
+		command = runner.GlobalReg.Get(h.Service)
+	}
+
+	if command == nil {
+		// services start after the loads, they read the exported file anyway
+		slog.Debug("export hook service not registered", "service", h.Service)
+
+		return nil
+	}
+
+	var err error
+	if h.Restart {
+		err = command.RestartProcess()
+	} else {
+		sig, errSig := runner.ParseSignal(h.Signal)
+		if errSig != nil {
+			return errSig
+		}
+
+		err = command.Signal(sig)
+	}
+
+	if errors.Is(err, runner.ErrNotRunning) {
+		slog.Debug("export hook service not running", "service", h.Service)
+
+		return nil
+	}
+
+	return err
+}
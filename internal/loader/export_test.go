@@ -0,0 +1,175 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rakunlabs/turna/pkg/runner"
+)
+
+func TestConfig_Export(t *testing.T) {
+	tempDir := t.TempDir()
+	marker := filepath.Join(tempDir, "hook.log")
+
+	c := &Config{
+		Name:   "app",
+		Export: filepath.Join(tempDir, "app.yaml"),
+		Exports: []ConfigExport{
+			{
+				Path:     filepath.Join(tempDir, "sidecar", ".env"),
+				FilePerm: "0600",
+				Hook: &ConfigExportHook{
+					Command: []string{"sh", "-c", "echo run >> " + marker},
+				},
+			},
+			{
+				Path:     filepath.Join(tempDir, "app.conf"),
+				Template: `listen {{ .app.port }}`,
+			},
+			{
+				Path:  filepath.Join(tempDir, "app.txt"),
+				Codec: "JSON",
+			},
+		},
+	}
+
+	to := &Data{Map: map[string]interface{}{"port": 8080}}
+	to.AddHold("app", to.Map)
+
+	if err := c.export(context.Background(), to); err != nil {
+		t.Fatalf("Config.export() error = %v", err)
+	}
+
+	wantFile := func(name, want string) {
+		t.Helper()
+
+		v, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(v) != want {
+			t.Errorf("export %s = %q, want %q", name, v, want)
+		}
+	}
+
+	wantFile("app.yaml", "port: 8080\n")
+	wantFile("sidecar/.env", "PORT=8080\n")
+	wantFile("app.conf", "listen 8080")
+	wantFile("app.txt", "{\n  \"port\": 8080\n}\n")
+	wantFile("hook.log", "run\n")
+
+	info, err := os.Stat(filepath.Join(tempDir, "sidecar", ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("export perm = %v, want 0600", info.Mode().Perm())
+	}
+
+	// unchanged content doesn't run the hook
+	if err := c.export(context.Background(), to); err != nil {
+		t.Fatalf("Config.export() error = %v", err)
+	}
+
+	wantFile("hook.log", "run\n")
+
+	to.Map["port"] = 9090
+
+	if err := c.export(context.Background(), to); err != nil {
+		t.Fatalf("Config.export() error = %v", err)
+	}
+
+	wantFile("sidecar/.env", "PORT=9090\n")
+	wantFile("hook.log", "run\nrun\n")
+
+	entries, err := os.ReadDir(filepath.Join(tempDir, "sidecar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("export left temp files: %v", entries)
+	}
+}
+
+func TestConfig_ExportHookService(t *testing.T) {
+	tempDir := t.TempDir()
+	marker := filepath.Join(tempDir, "signal.log")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	reg := runner.NewStoreReg(wg)
+	prevReg := runner.GlobalReg
+	reg.SetAsGlobal()
+	defer func() { runner.GlobalReg = prevReg }()
+
+	c := &Config{
+		Name: "app",
+		Exports: []ConfigExport{
+			{
+				Path: filepath.Join(tempDir, "app.yaml"),
+				Hook: &ConfigExportHook{Service: "app", Signal: "SIGHUP"},
+			},
+		},
+	}
+
+	to := &Data{Map: map[string]interface{}{"port": 8080}}
+
+	// service is not registered yet
+	if err := c.export(ctx, to); err != nil {
+		t.Fatalf("Config.export() error = %v", err)
+	}
+
+	command := &runner.Command{
+		Name:         "app",
+		Command:      []string{"sh", "-c", `trap "echo hup >> ` + marker + `" HUP; echo ready >> ` + marker + `; while true; do sleep 0.1; done`},
+		AllowFailure: true,
+	}
+
+	if err := reg.Add(command); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_ = command.Run(ctx)
+	}()
+
+	waitMarker := func(want string) {
+		t.Helper()
+
+		for range 50 {
+			if v, _ := os.ReadFile(marker); strings.TrimSpace(string(v)) == want {
+				return
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		v, _ := os.ReadFile(marker)
+		t.Fatalf("signal log = %q, want %q", v, want)
+	}
+
+	waitMarker("ready")
+
+	to.Map["port"] = 9090
+
+	if err := c.export(ctx, to); err != nil {
+		t.Fatalf("Config.export() error = %v", err)
+	}
+
+	waitMarker("ready\nhup")
+}
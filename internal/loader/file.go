@@ -1,11 +1,18 @@
 package loader
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 const (
@@ -13,6 +20,9 @@ const (
 	defaultFolderPerm fs.FileMode = 0o755
 )
 
+// fileWatchDebounce groups the burst of events a single file update creates.
+var fileWatchDebounce = 100 * time.Millisecond
+
 // loadFileRaw reads a file and returns its raw content.
 func loadFileRaw(path string) ([]byte, error) {
 	b, err := os.ReadFile(path)
@@ -23,85 +33,170 @@ func loadFileRaw(path string) ([]byte, error) {
 	return b, nil
 }
 
-// loadFileMap reads a file and decodes it into a map based on its extension.
-func loadFileMap(path string, v any) error {
+// decodeFileContent decodes data, the content of path, into v based on the
+// file extension.
+func decodeFileContent(path string, data []byte, v any) error {
 	c, err := codecByExt(filepath.Ext(path))
 	if err != nil {
 		return err
 	}
 
-	f, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", path, err)
-	}
-	defer f.Close()
-
-	if err := c.Decode(f, v); err != nil {
+	if err := c.Decode(bytes.NewReader(data), v); err != nil {
 		return fmt.Errorf("failed to decode file %s: %w", path, err)
 	}
 
 	return nil
 }
 
-// writeRaw writes raw bytes to path, creating parent folders as needed.
-func writeRaw(path string, data []byte, filePerm, folderPerm string) error {
-	f, err := openForWrite(path, filePerm, folderPerm)
+// dynamicFile watches path and returns a channel with the file content,
+// starting with the current content and followed by every change.
+//
+// The parent folder is watched instead of the file so atomic replacements are
+// detected, including the symlink swap Kubernetes does for ConfigMap and
+// Secret volumes. Events are debounced and the content is compared with the
+// last sent value, so only real changes are delivered.
+//
+// The returned channel is closed when ctx is cancelled. The stop function
+// stops the underlying watcher.
+func dynamicFile(ctx context.Context, wg *sync.WaitGroup, path string) (<-chan []byte, func(), error) {
+	last, err := loadFileRaw(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer f.Close()
 
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", path, err)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
-	return nil
-}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
 
-// writeCodec writes data to path encoded by the file extension's codec.
-func writeCodec(path string, data any, filePerm, folderPerm string) error {
-	c, err := codecByExt(filepath.Ext(path))
-	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("failed to watch folder of %s: %w", path, err)
 	}
 
-	f, err := openForWrite(path, filePerm, folderPerm)
+	// unbuffered: only the latest change matters
+	vChannel := make(chan []byte)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(vChannel)
+		defer watcher.Close()
+
+		send := func(data []byte) bool {
+			select {
+			case vChannel <- data:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !send(last) {
+			return
+		}
+
+		debounce := time.NewTimer(fileWatchDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				debounce.Reset(fileWatchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				slog.Warn("file watcher error", "path", path, "err", err.Error())
+			case <-debounce.C:
+				data, err := loadFileRaw(path)
+				if err != nil {
+					// file can be missing for a moment while it is replaced
+					slog.Warn("failed to read watched file", "path", path, "err", err.Error())
+
+					continue
+				}
+
+				if bytes.Equal(data, last) {
+					continue
+				}
+
+				last = data
+
+				if !send(data) {
+					return
+				}
+			}
+		}
+	}()
+
+	return vChannel, func() { watcher.Close() }, nil
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// over path, so readers never see a partial file. Parent folders are created
+// as needed. It reports false without writing when the content is unchanged.
+func writeAtomic(path string, data []byte, filePerm, folderPerm string) (bool, error) {
+	perm, err := parsePerm(filePerm, defaultFilePerm)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer f.Close()
 
-	if err := c.Encode(f, data); err != nil {
-		return fmt.Errorf("failed to encode file %s: %w", path, err)
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm() == perm.Perm() {
+			return false, nil
+		}
 	}
 
-	return nil
-}
-
-func openForWrite(path, filePerm, folderPerm string) (*os.File, error) {
 	folder := filepath.Dir(path)
 	if _, err := os.Stat(folder); os.IsNotExist(err) {
 		perm, err := parsePerm(folderPerm, defaultFolderPerm)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 
 		if err := os.MkdirAll(folder, perm); err != nil {
-			return nil, fmt.Errorf("failed to create folder %s: %w", folder, err)
+			return false, fmt.Errorf("failed to create folder %s: %w", folder, err)
 		}
 	}
 
-	perm, err := parsePerm(filePerm, defaultFilePerm)
+	f, err := os.CreateTemp(folder, "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("failed to create temp file for %s: %w", path, err)
 	}
 
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+
+		return false, fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+
+		return false, fmt.Errorf("failed to set permission of %s: %w", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return false, fmt.Errorf("failed to replace file %s: %w", path, err)
 	}
 
-	return f, nil
+	return true, nil
 }
 
 // parsePerm parses an octal permission string such as "0644".
@@ -1,16 +1,25 @@
 package loader
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	okclient "github.com/rakunlabs/ok"
 )
 
+const (
+	defaultHTTPInterval   = 30 * time.Second
+	defaultHTTPBackoffMax = 5 * time.Minute
+)
+
 // httpClient is a small wrapper over the ok HTTP client used by loads.
 //
 // Clients are created lazily and cached by their TLS verification mode so
@@ -46,13 +55,37 @@ func (c *httpClient) get(insecure bool) (*okclient.Client, error) {
 	return c.secure, nil
 }
 
+// httpResponse is the result of a single request made by httpClient.
+type httpResponse struct {
+	Data        []byte
+	ContentType string
+	// ETag and LastModified are the cache validators sent back on the next
+	// conditional request.
+	ETag         string
+	LastModified string
+	// NotModified is set when the server answered 304 to a conditional request.
+	NotModified bool
+}
+
 // loadRaw fetches the configured URL and returns the body and Content-Type.
 func (c *httpClient) loadRaw(ctx context.Context, cfg *ConfigHTTP) ([]byte, string, error) {
-	client, err := c.get(cfg.InsecureSkipVerify)
+	resp, err := c.load(ctx, cfg, nil)
 	if err != nil {
 		return nil, "", err
 	}
 
+	return resp.Data, resp.ContentType, nil
+}
+
+// load fetches the configured URL. When last is not nil its validators are
+// sent as If-None-Match/If-Modified-Since and a 304 answer is reported with
+// NotModified.
+func (c *httpClient) load(ctx context.Context, cfg *ConfigHTTP, last *httpResponse) (*httpResponse, error) {
+	client, err := c.get(cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.Timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
@@ -66,7 +99,7 @@ func (c *httpClient) loadRaw(ctx context.Context, cfg *ConfigHTTP) ([]byte, stri
 
 	reqURL, err := buildURL(cfg.URL, cfg.Query)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	var body io.Reader
@@ -76,19 +109,32 @@ func (c *httpClient) loadRaw(ctx context.Context, cfg *ConfigHTTP) ([]byte, stri
 
 	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	for k, v := range cfg.Headers {
 		req.Header.Set(k, v)
 	}
 
-	var (
-		data        []byte
-		contentType string
-	)
+	if last != nil {
+		if last.ETag != "" {
+			req.Header.Set("If-None-Match", last.ETag)
+		}
+
+		if last.LastModified != "" {
+			req.Header.Set("If-Modified-Since", last.LastModified)
+		}
+	}
+
+	resp := &httpResponse{}
 
 	if err := client.Do(req, func(r *http.Response) error {
+		if r.StatusCode == http.StatusNotModified && last != nil {
+			resp.NotModified = true
+
+			return nil
+		}
+
 		if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
 			return okclient.ErrResponse(r)
 		}
@@ -98,15 +144,130 @@ func (c *httpClient) loadRaw(ctx context.Context, cfg *ConfigHTTP) ([]byte, stri
 			return fmt.Errorf("failed to read response body: %w", err)
 		}
 
-		data = b
-		contentType = r.Header.Get("Content-Type")
+		resp.Data = b
+		resp.ContentType = r.Header.Get("Content-Type")
+		resp.ETag = r.Header.Get("ETag")
+		resp.LastModified = r.Header.Get("Last-Modified")
 
 		return nil
 	}); err != nil {
-		return nil, "", fmt.Errorf("failed to do http request %s: %w", cfg.URL, err)
+		return nil, fmt.Errorf("failed to do http request %s: %w", cfg.URL, err)
+	}
+
+	return resp, nil
+}
+
+// dynamicValue polls the configured URL and returns a channel with every
+// changed response, starting with the current one.
+//
+// Requests are conditional so a 304 answer skips the work, and bodies equal
+// to the last one are dropped for servers without validators. Failed polls
+// are retried with an exponential backoff up to cfg.BackoffMax and never
+// reach the channel, so the last good value stays in place.
+//
+// The first request is made before returning and its error is returned. The
+// channel is closed when ctx is cancelled or the stop function is called.
+func (c *httpClient) dynamicValue(ctx context.Context, wg *sync.WaitGroup, cfg *ConfigHTTP) (<-chan *httpResponse, func(), error) {
+	last, err := c.load(ctx, cfg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultHTTPInterval
+	}
+
+	backoffMax := cfg.BackoffMax
+	if backoffMax <= 0 {
+		backoffMax = defaultHTTPBackoffMax
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// unbuffered: only the latest change matters
+	vChannel := make(chan *httpResponse)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(vChannel)
+
+		send := func(resp *httpResponse) bool {
+			select {
+			case vChannel <- resp:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !send(last) {
+			return
+		}
+
+		failures := 0
+
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			resp, err := c.load(ctx, cfg, last)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+				wait := backoff(interval, backoffMax, failures)
+
+				slog.Warn("failed to poll http config, keeping last value", "url", cfg.URL, "retry", wait.String(), "err", err.Error())
+
+				timer.Reset(wait)
+
+				continue
+			}
+
+			failures = 0
+
+			timer.Reset(interval)
+
+			if resp.NotModified {
+				continue
+			}
+
+			changed := !bytes.Equal(resp.Data, last.Data)
+
+			// an unchanged body can come with new validators for the next request
+			last = resp
+
+			if !changed {
+				continue
+			}
+
+			if !send(resp) {
+				return
+			}
+		}
+	}()
+
+	return vChannel, cancel, nil
+}
+
+// backoff returns base doubled for every failure, capped at limit.
+func backoff(base, limit time.Duration, failures int) time.Duration {
+	wait := base
+	for i := 0; i < failures && wait < limit; i++ {
+		wait *= 2
 	}
 
-	return data, contentType, nil
+	return min(wait, limit)
 }
 
 // buildURL merges the given query parameters into rawURL.
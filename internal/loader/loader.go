@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"path"
 	"sync"
 )
@@ -17,8 +18,18 @@ type Call func(context.Context, string, map[string]interface{})
 // clients holds the lazily-connected backends shared across a Load call.
 type clients struct {
 	consul *consulClient
-	vault  *vaultClient
+	vault  *vaultClients
 	http   *httpClient
+	etcd   *etcdClients
+}
+
+func newClients() *clients {
+	return &clients{
+		consul: &consulClient{},
+		vault:  &vaultClients{},
+		http:   &httpClient{},
+		etcd:   &etcdClients{},
+	}
 }
 
 // Load loads all configs to their export location and in memory.
@@ -29,11 +40,7 @@ func (c Configs) Load(ctx context.Context, wg *sync.WaitGroup, call Call) error
 		wg = &sync.WaitGroup{}
 	}
 
-	cl := &clients{
-		consul: &consulClient{},
-		vault:  &vaultClient{},
-		http:   &httpClient{},
-	}
+	cl := newClients()
 
 	for _, config := range c {
 		if err := config.load(ctx, wg, cl, call); err != nil {
@@ -47,6 +54,19 @@ func (c Configs) Load(ctx context.Context, wg *sync.WaitGroup, call Call) error
 func (c Config) load(ctx context.Context, wg *sync.WaitGroup, cl *clients, call Call) error {
 	to := Data{}
 
+	if c.Schema != nil {
+		schema, err := c.Schema.compile(c.Name)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", c.Name, err)
+		}
+
+		c.schema = schema
+	}
+
+	if c.Resolve {
+		c.resolver = &Resolver{cl: cl}
+	}
+
 	for _, static := range c.Statics {
 		if err := static.load(ctx, &to, cl); err != nil {
 			return err
@@ -54,8 +74,10 @@ func (c Config) load(ctx context.Context, wg *sync.WaitGroup, cl *clients, call
 	}
 
 	if len(c.Dynamics) > 0 {
-		for _, dynamic := range c.Dynamics {
-			waitCtx, err := dynamic.load(ctx, wg, &to, cl, &c, call)
+		state := newDynamicData(to, len(c.Dynamics))
+
+		for i, dynamic := range c.Dynamics {
+			waitCtx, err := dynamic.load(ctx, wg, state.source(i), cl, &c, call)
 			if err != nil {
 				return err
 			}
@@ -69,22 +91,22 @@ func (c Config) load(ctx context.Context, wg *sync.WaitGroup, cl *clients, call
 		return nil
 	}
 
+	if err := c.resolve(ctx, &to); err != nil {
+		return err
+	}
+
+	if err := c.validate(&to); err != nil {
+		return err
+	}
+
 	if to.Raw != nil {
 		to.AddHold(c.Name, to.Raw)
 	} else {
 		to.AddHold(c.Name, to.Map)
 	}
 
-	if c.Export != "" {
-		if to.Raw != nil {
-			if err := writeRaw(c.Export, to.Raw, c.FilePerm, c.FolderPerm); err != nil {
-				return err
-			}
-		} else {
-			if err := writeCodec(c.Export, to.Map, c.FilePerm, c.FolderPerm); err != nil {
-				return err
-			}
-		}
+	if err := c.export(ctx, &to); err != nil {
+		return err
 	}
 
 	if call != nil {
@@ -125,6 +147,24 @@ func (c ConfigStatic) load(ctx context.Context, to *Data, cl *clients) error {
 		}
 	}
 
+	if c.Env != nil {
+		if err := c.loadEnv(to); err != nil {
+			return err
+		}
+	}
+
+	if c.ConsulCatalog != nil {
+		if err := c.loadConsulCatalog(ctx, to, cl); err != nil {
+			return err
+		}
+	}
+
+	if c.Etcd != nil {
+		if err := c.loadEtcd(ctx, to, cl); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -150,7 +190,9 @@ func (c ConfigStatic) loadConsul(ctx context.Context, to *Data, cl *clients) err
 	if c.Consul.Raw {
 		if c.Consul.Map != "" {
 			vMap := MapPath(c.Consul.Map, data).(map[string]interface{})
-			to.Merge(vMap)
+			if err := to.Merge(vMap, c.Consul.Merge); err != nil {
+				return err
+			}
 			dataProcessed = vMap
 		} else {
 			to.Raw = data
@@ -164,7 +206,9 @@ func (c ConfigStatic) loadConsul(ctx context.Context, to *Data, cl *clients) err
 
 		innerValue := MapPath(c.Consul.Map, InnerPath(c.Consul.InnerPath, vMap))
 		if m, ok := innerValue.(map[string]interface{}); ok {
-			to.Merge(m)
+			if err := to.Merge(m, c.Consul.Merge); err != nil {
+				return err
+			}
 			dataProcessed = innerValue
 		} else {
 			to.Raw = []byte(fmt.Sprint(innerValue))
@@ -186,14 +230,20 @@ func (c ConfigStatic) loadConsul(ctx context.Context, to *Data, cl *clients) err
 }
 
 func (c ConfigStatic) loadVault(ctx context.Context, to *Data, cl *clients) error {
-	cl.vault.appRoleBasePath = c.Vault.AppRoleBasePath
-
-	vMap, err := cl.vault.loadMap(ctx, c.Vault.PathPrefix, c.Vault.Path)
+	_, vMap, err := cl.vault.get(c.Vault).loadSecret(ctx, c.Vault)
 	if err != nil {
 		return err
 	}
 
-	if c.Vault.Template {
+	return c.Vault.process(to, vMap)
+}
+
+// process runs the vault pipeline over the secret data vMap. It is shared by
+// static loads and dynamic watches.
+func (c *ConfigVault) process(to *Data, vMap map[string]interface{}) error {
+	var err error
+
+	if c.Template {
 		data, err := json.Marshal(vMap)
 		if err != nil {
 			return err
@@ -213,16 +263,18 @@ func (c ConfigStatic) loadVault(ctx context.Context, to *Data, cl *clients) erro
 	}
 
 	var dataProcessed interface{}
-	innerValue := MapPath(c.Vault.Map, InnerPath(c.Vault.InnerPath, vMap))
+	innerValue := MapPath(c.Map, InnerPath(c.InnerPath, vMap))
 	if m, ok := innerValue.(map[string]interface{}); ok {
-		to.Merge(m)
+		if err := to.Merge(m, c.Merge); err != nil {
+			return err
+		}
 		dataProcessed = innerValue
 	} else {
 		to.Raw = []byte(fmt.Sprint(innerValue))
 		dataProcessed = to.Raw
 	}
 
-	if c.Vault.Base64 && to.Raw != nil {
+	if c.Base64 && to.Raw != nil {
 		if to.Raw, err = base64.StdEncoding.DecodeString(string(to.Raw)); err != nil {
 			return fmt.Errorf("vault decode base64 error: %w", err)
 		}
@@ -230,7 +282,7 @@ func (c ConfigStatic) loadVault(ctx context.Context, to *Data, cl *clients) erro
 		dataProcessed = to.Raw
 	}
 
-	to.AddHold(c.Vault.Name, dataProcessed)
+	to.AddHold(c.Name, dataProcessed)
 
 	return nil
 }
@@ -241,7 +293,21 @@ func (c ConfigStatic) loadFile(to *Data) error {
 		return err
 	}
 
-	if c.File.Template {
+	return c.File.process(to, data)
+}
+
+// process runs the file pipeline over data, which is the content of the file
+// at c.Path. It is shared by static loads and dynamic file watches.
+func (c *ConfigFile) process(to *Data, data []byte) error {
+	var err error
+
+	if c.Decrypt != nil {
+		if data, err = c.Decrypt.decrypt(data); err != nil {
+			return fmt.Errorf("file %s decrypt error: %w", c.Path, err)
+		}
+	}
+
+	if c.Template {
 		v, err := renderTemplate(string(data), to.Hold)
 		if err != nil {
 			return err
@@ -252,10 +318,12 @@ func (c ConfigStatic) loadFile(to *Data) error {
 
 	var dataProcessed interface{}
 
-	if c.File.Raw {
-		if c.File.Map != "" {
-			vMap := MapPath(c.File.Map, data).(map[string]interface{})
-			to.Merge(vMap)
+	if c.Raw {
+		if c.Map != "" {
+			vMap := MapPath(c.Map, data).(map[string]interface{})
+			if err := to.Merge(vMap, c.Merge); err != nil {
+				return err
+			}
 			dataProcessed = vMap
 		} else {
 			to.Raw = data
@@ -263,13 +331,15 @@ func (c ConfigStatic) loadFile(to *Data) error {
 		}
 	} else {
 		var vMap map[string]interface{}
-		if err := loadFileMap(c.File.Path, &vMap); err != nil {
+		if err := decodeFileContent(c.Path, data, &vMap); err != nil {
 			return err
 		}
 
-		innerValue := MapPath(c.File.Map, InnerPath(c.File.InnerPath, vMap))
+		innerValue := MapPath(c.Map, InnerPath(c.InnerPath, vMap))
 		if m, ok := innerValue.(map[string]interface{}); ok {
-			to.Merge(m)
+			if err := to.Merge(m, c.Merge); err != nil {
+				return err
+			}
 			dataProcessed = innerValue
 		} else {
 			to.Raw = []byte(fmt.Sprint(innerValue))
@@ -277,7 +347,7 @@ func (c ConfigStatic) loadFile(to *Data) error {
 		}
 	}
 
-	if c.File.Base64 && to.Raw != nil {
+	if c.Base64 && to.Raw != nil {
 		if to.Raw, err = base64.StdEncoding.DecodeString(string(to.Raw)); err != nil {
 			return fmt.Errorf("file decode base64 error: %w", err)
 		}
@@ -285,7 +355,7 @@ func (c ConfigStatic) loadFile(to *Data) error {
 		dataProcessed = to.Raw
 	}
 
-	to.AddHold(c.File.Name, dataProcessed)
+	to.AddHold(c.Name, dataProcessed)
 
 	return nil
 }
@@ -296,7 +366,15 @@ func (c ConfigStatic) loadHTTP(ctx context.Context, to *Data, cl *clients) error
 		return err
 	}
 
-	if c.HTTP.Template {
+	return c.HTTP.process(to, data, contentType)
+}
+
+// process runs the http pipeline over data, the response body with its
+// Content-Type. It is shared by static loads and dynamic polling.
+func (c *ConfigHTTP) process(to *Data, data []byte, contentType string) error {
+	var err error
+
+	if c.Template {
 		v, err := renderTemplate(string(data), to.Hold)
 		if err != nil {
 			return err
@@ -307,17 +385,19 @@ func (c ConfigStatic) loadHTTP(ctx context.Context, to *Data, cl *clients) error
 
 	var dataProcessed interface{}
 
-	if c.HTTP.Raw {
-		if c.HTTP.Map != "" {
-			vMap := MapPath(c.HTTP.Map, data).(map[string]interface{})
-			to.Merge(vMap)
+	if c.Raw {
+		if c.Map != "" {
+			vMap := MapPath(c.Map, data).(map[string]interface{})
+			if err := to.Merge(vMap, c.Merge); err != nil {
+				return err
+			}
 			dataProcessed = vMap
 		} else {
 			to.Raw = data
 			dataProcessed = data
 		}
 	} else {
-		codecName := c.HTTP.Codec
+		codecName := c.Codec
 		if codecName == "" {
 			codecName = codecNameByContentType(contentType)
 		}
@@ -327,9 +407,11 @@ func (c ConfigStatic) loadHTTP(ctx context.Context, to *Data, cl *clients) error
 			return err
 		}
 
-		innerValue := MapPath(c.HTTP.Map, InnerPath(c.HTTP.InnerPath, vMap))
+		innerValue := MapPath(c.Map, InnerPath(c.InnerPath, vMap))
 		if m, ok := innerValue.(map[string]interface{}); ok {
-			to.Merge(m)
+			if err := to.Merge(m, c.Merge); err != nil {
+				return err
+			}
 			dataProcessed = innerValue
 		} else {
 			to.Raw = []byte(fmt.Sprint(innerValue))
@@ -337,7 +419,7 @@ func (c ConfigStatic) loadHTTP(ctx context.Context, to *Data, cl *clients) error
 		}
 	}
 
-	if c.HTTP.Base64 && to.Raw != nil {
+	if c.Base64 && to.Raw != nil {
 		if to.Raw, err = base64.StdEncoding.DecodeString(string(to.Raw)); err != nil {
 			return fmt.Errorf("http decode base64 error: %w", err)
 		}
@@ -345,13 +427,22 @@ func (c ConfigStatic) loadHTTP(ctx context.Context, to *Data, cl *clients) error
 		dataProcessed = to.Raw
 	}
 
-	to.AddHold(c.HTTP.Name, dataProcessed)
+	to.AddHold(c.Name, dataProcessed)
 
 	return nil
 }
 
 func (c ConfigStatic) loadContent(to *Data) error {
 	content := c.Content.Content
+	if c.Content.Decrypt != nil {
+		v, err := c.Content.Decrypt.decrypt([]byte(content))
+		if err != nil {
+			return fmt.Errorf("content decrypt error: %w", err)
+		}
+
+		content = string(v)
+	}
+
 	if c.Content.Template {
 		v, err := renderTemplate(content, to.Hold)
 		if err != nil {
@@ -366,7 +457,9 @@ func (c ConfigStatic) loadContent(to *Data) error {
 	if c.Content.Raw {
 		if c.Content.Map != "" {
 			vMap := MapPath(c.Content.Map, []byte(content)).(map[string]interface{})
-			to.Merge(vMap)
+			if err := to.Merge(vMap, c.Content.Merge); err != nil {
+				return err
+			}
 			dataProcessed = vMap
 		} else {
 			to.Raw = []byte(content)
@@ -380,7 +473,9 @@ func (c ConfigStatic) loadContent(to *Data) error {
 
 		innerValue := MapPath(c.Content.Map, InnerPath(c.Content.InnerPath, vMap))
 		if m, ok := innerValue.(map[string]interface{}); ok {
-			to.Merge(m)
+			if err := to.Merge(m, c.Content.Merge); err != nil {
+				return err
+			}
 			dataProcessed = innerValue
 		} else {
 			to.Raw = []byte(fmt.Sprint(innerValue))
@@ -402,15 +497,30 @@ func (c ConfigStatic) loadContent(to *Data) error {
 	return nil
 }
 
-func (c ConfigDynamic) load(ctx context.Context, wg *sync.WaitGroup, to *Data, cl *clients, config *Config, call Call) (context.Context, error) {
+func (c ConfigDynamic) load(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
 	if wg == nil {
 		wg = &sync.WaitGroup{}
 	}
 
-	if c.Consul == nil {
-		return nil, nil
+	switch {
+	case c.Consul != nil:
+		return c.loadConsul(ctx, wg, to, cl, config, call)
+	case c.File != nil:
+		return c.loadFile(ctx, wg, to, config, call)
+	case c.HTTP != nil:
+		return c.loadHTTP(ctx, wg, to, cl, config, call)
+	case c.Vault != nil:
+		return c.loadVault(ctx, wg, to, cl, config, call)
+	case c.ConsulCatalog != nil:
+		return c.loadConsulCatalog(ctx, wg, to, cl, config, call)
+	case c.Etcd != nil:
+		return c.loadEtcd(ctx, wg, to, cl, config, call)
 	}
 
+	return nil, nil
+}
+
+func (c ConfigDynamic) loadConsul(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
 	contentPath := path.Join(c.Consul.PathPrefix, c.Consul.Path)
 
 	ch, cancel, err := cl.consul.dynamicValue(ctx, wg, contentPath)
@@ -418,14 +528,195 @@ func (c ConfigDynamic) load(ctx context.Context, wg *sync.WaitGroup, to *Data, c
 		return nil, err
 	}
 
-	waitContext, waitCancel := context.WithCancel(ctx)
+	return watchDynamic(ctx, wg, ch, cancel, to, config, call, func(to *Data, data []byte) error {
+		if c.Consul.Template {
+			v, err := renderTemplate(string(data), to.Hold)
+			if err != nil {
+				return fmt.Errorf("failed to execute consul template: %w", err)
+			}
 
-	recordToMap := copyMap(to.Map)
+			data = v
+		}
+
+		if c.Consul.Raw {
+			to.Raw = data
+			to.AddHold(c.Consul.Name, data)
+
+			return nil
+		}
+
+		var vMap map[string]interface{}
+		if err := decodeContent(c.Consul.Codec, data, &vMap); err != nil {
+			return fmt.Errorf("failed to load consul data: %w", err)
+		}
+
+		if err := to.Merge(vMap, c.Consul.Merge); err != nil {
+			return err
+		}
+		to.AddHold(c.Consul.Name, vMap)
+
+		return nil
+	}), nil
+}
+
+func (c ConfigDynamic) loadFile(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, config *Config, call Call) (context.Context, error) {
+	ch, cancel, err := dynamicFile(ctx, wg, c.File.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchDynamic(ctx, wg, ch, cancel, to, config, call, func(to *Data, data []byte) error {
+		if err := c.File.process(to, data); err != nil {
+			return fmt.Errorf("failed to load file data: %w", err)
+		}
+
+		return nil
+	}), nil
+}
+
+func (c ConfigDynamic) loadHTTP(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
+	ch, cancel, err := cl.http.dynamicValue(ctx, wg, c.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchDynamic(ctx, wg, ch, cancel, to, config, call, func(to *Data, resp *httpResponse) error {
+		if err := c.HTTP.process(to, resp.Data, resp.ContentType); err != nil {
+			return fmt.Errorf("failed to load http data: %w", err)
+		}
+
+		return nil
+	}), nil
+}
+
+func (c ConfigDynamic) loadVault(ctx context.Context, wg *sync.WaitGroup, to *dynamicSource, cl *clients, config *Config, call Call) (context.Context, error) {
+	ch, cancel, err := cl.vault.get(c.Vault).dynamicValue(ctx, wg, c.Vault)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchDynamic(ctx, wg, ch, cancel, to, config, call, func(to *Data, vMap map[string]interface{}) error {
+		// the secret is kept for the next rebuild, merge a copy
+		if err := c.Vault.process(to, copyMap(vMap)); err != nil {
+			return fmt.Errorf("failed to load vault data: %w", err)
+		}
+
+		return nil
+	}), nil
+}
+
+// dynamicData is the data of a config with dynamic sources. Every update
+// rebuilds it from the statics and the latest value of each source, so a
+// source never drops the values of the others.
+type dynamicData struct {
+	mutex sync.Mutex
+	// base is the data of the statics.
+	base Data
+	// sources applies the latest value of each dynamic source, nil until the
+	// first value.
+	sources []func(*Data) error
+}
+
+// dynamicSource is a dynamic source of a dynamicData.
+type dynamicSource struct {
+	state *dynamicData
+	index int
+}
+
+func newDynamicData(base Data, sources int) *dynamicData {
+	return &dynamicData{base: base, sources: make([]func(*Data) error, sources)}
+}
+
+func (d *dynamicData) source(index int) *dynamicSource {
+	return &dynamicSource{state: d, index: index}
+}
+
+// build applies the sources in order to a copy of the statics.
+func (d *dynamicData) build() (*Data, error) {
+	to := &Data{
+		Map:  copyMap(d.base.Map),
+		Raw:  d.base.Raw,
+		Hold: maps.Clone(d.base.Hold),
+	}
+
+	for _, apply := range d.sources {
+		if apply == nil {
+			continue
+		}
+
+		if err := apply(to); err != nil {
+			return nil, err
+		}
+	}
+
+	return to, nil
+}
+
+// update sets the latest value of the source then resolves, validates,
+// exports and calls with the rebuilt data. A rejected value keeps the previous one.
+func (s *dynamicSource) update(ctx context.Context, config *Config, call Call, apply func(*Data) error) {
+	d := s.state
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	prev := d.sources[s.index]
+	d.sources[s.index] = apply
+
+	to, err := d.build()
+	if err != nil {
+		slog.Warn("failed to process dynamic data", "load", config.Name, "err", err.Error())
+
+		d.sources[s.index] = prev
+
+		return
+	}
+
+	if err := config.resolve(ctx, to); err != nil {
+		slog.Warn("failed to resolve dynamic data", "load", config.Name, "err", err.Error())
+
+		d.sources[s.index] = prev
+
+		return
+	}
+
+	if err := config.validate(to); err != nil {
+		slog.Warn("dynamic data rejected by schema", "load", config.Name, "err", err.Error())
+
+		d.sources[s.index] = prev
+
+		return
+	}
+
+	if to.Raw != nil {
+		to.AddHold(config.Name, to.Raw)
+	} else {
+		to.AddHold(config.Name, to.Map)
+	}
+
+	if err := config.export(ctx, to); err != nil {
+		slog.Warn("failed to export dynamic data", "load", config.Name, "err", err.Error())
+	}
+
+	if call != nil {
+		call(ctx, config.Name, to.Hold)
+	}
+}
+
+// watchDynamic applies every value received from ch with process, then
+// re-exports the config and invokes call. A failing process keeps the previous
+// exported value.
+//
+// The returned context is cancelled once the first value has been received so
+// the caller can wait for the initial load.
+func watchDynamic[T any](ctx context.Context, wg *sync.WaitGroup, ch <-chan T, cancel func(), to *dynamicSource, config *Config, call Call, process func(*Data, T) error) context.Context {
+	waitContext, waitCancel := context.WithCancel(ctx)
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer cancel()
+		defer waitCancel()
 
 		// release the startup wait once the first message has been received,
 		// regardless of whether it processed cleanly.
@@ -439,63 +730,19 @@ func (c ConfigDynamic) load(ctx context.Context, wg *sync.WaitGroup, to *Data, c
 			select {
 			case <-ctx.Done():
 				return
-			case data := <-ch:
-				received = true
-
-				if c.Consul.Template {
-					v, err := renderTemplate(string(data), to.Hold)
-					if err != nil {
-						slog.Warn("failed to execute consul template", "err", err.Error())
-
-						continue
-					}
-
-					data = v
+			case data, ok := <-ch:
+				if !ok {
+					return
 				}
 
-				if c.Consul.Raw {
-					to.Raw = data
-					to.AddHold(c.Consul.Name, data)
-				} else {
-					var vMap map[string]interface{}
-					if err := decodeContent(c.Consul.Codec, data, &vMap); err != nil {
-						slog.Warn("failed to load consul data", "err", err.Error())
-
-						continue
-					}
-
-					// restore the static base before merging the new value
-					to.Map = copyMap(recordToMap)
-					to.Merge(vMap)
-					to.AddHold(c.Consul.Name, vMap)
-				}
-
-				if to.Raw != nil {
-					to.AddHold(config.Name, to.Raw)
-				} else {
-					to.AddHold(config.Name, to.Map)
-				}
-
-				if config.Export != "" {
-					if err := c.exportDynamic(config, to); err != nil {
-						slog.Warn("failed to save dynamic consul data to file", "filePath", config.Export, "err", err.Error())
-					}
-				}
+				received = true
 
-				if call != nil {
-					call(ctx, config.Name, to.Hold)
-				}
+				to.update(ctx, config, call, func(to *Data) error {
+					return process(to, data)
+				})
 			}
 		}
 	}()
 
-	return waitContext, nil
-}
-
-func (c ConfigDynamic) exportDynamic(config *Config, to *Data) error {
-	if to.Raw != nil {
-		return writeRaw(config.Export, to.Raw, config.FilePerm, config.FolderPerm)
-	}
-
-	return writeCodec(config.Export, to.Map, config.FilePerm, config.FolderPerm)
+	return waitContext
 }
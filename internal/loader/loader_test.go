@@ -2,9 +2,14 @@ package loader
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -242,3 +247,385 @@ func TestConfigs_LoadHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigs_LoadDynamicFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// mimic the layout of a Kubernetes ConfigMap volume
+	writeVersion := func(version, content string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Join(tempDir, version), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(tempDir, version, "config.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink(version, filepath.Join(tempDir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Rename(filepath.Join(tempDir, "..data_tmp"), filepath.Join(tempDir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeVersion("..v1", "server:\n  address: \":8080\"\n")
+
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(tempDir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	c := Configs{
+		{
+			Name:   "app",
+			Export: filepath.Join(tempDir, "out", "app.json"),
+			Dynamics: []ConfigDynamic{
+				{
+					File: &ConfigFile{
+						Path:      filepath.Join(tempDir, "config.yaml"),
+						InnerPath: "server",
+					},
+				},
+			},
+		},
+	}
+
+	if err := c.Load(ctx, wg, func(_ context.Context, _ string, data map[string]interface{}) {
+		calls <- data
+	}); err != nil {
+		t.Fatalf("Configs.Load() error = %v", err)
+	}
+
+	wantCall := func(address string) {
+		t.Helper()
+
+		select {
+		case data := <-calls:
+			got := data["app"].(map[string]interface{})["address"]
+			if got != address {
+				t.Fatalf("Configs.Load() address = %v, want %v", got, address)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Configs.Load() no call for address %v", address)
+		}
+
+		v, err := os.ReadFile(filepath.Join(tempDir, "out", "app.json"))
+		if err != nil {
+			t.Fatalf("Configs.Load() read export error = %v", err)
+		}
+
+		want := "{\n  \"address\": \"" + address + "\"\n}\n"
+		if string(v) != want {
+			t.Errorf("Configs.Load() content = \n%q\n, want \n%q\n", string(v), want)
+		}
+	}
+
+	wantCall(":8080")
+
+	writeVersion("..v2", "server:\n  address: \":9090\"\n")
+
+	wantCall(":9090")
+}
+
+func TestConfigs_LoadDynamicFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.yaml", "a: 0\n")
+	write("b.yaml", "b: 0\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	c := Configs{
+		{
+			Name:   "app",
+			Export: filepath.Join(tempDir, "out", "app.json"),
+			Dynamics: []ConfigDynamic{
+				{File: &ConfigFile{Path: filepath.Join(tempDir, "a.yaml")}},
+				{File: &ConfigFile{Path: filepath.Join(tempDir, "b.yaml")}},
+			},
+		},
+	}
+
+	if err := c.Load(ctx, wg, func(_ context.Context, _ string, data map[string]interface{}) {
+		calls <- data
+	}); err != nil {
+		t.Fatalf("Configs.Load() error = %v", err)
+	}
+
+	// a change of a source keeps the values of the other sources
+	exportPath := filepath.Join(tempDir, "out", "app.json")
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"a": 0, "b": 0})
+
+	write("a.yaml", "a: 1\n")
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"a": 1, "b": 0})
+
+	write("b.yaml", "b: 2\n")
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"a": 1, "b": 2})
+}
+
+func TestConfigs_LoadDynamicHTTPMerge(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 0
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"a": ` + strconv.Itoa(version) + `}`))
+	}))
+	defer srv.Close()
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "b.yaml"), []byte("b: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	exportPath := filepath.Join(tempDir, "out", "app.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	c := Configs{
+		{
+			Name:   "app",
+			Export: exportPath,
+			Dynamics: []ConfigDynamic{
+				{File: &ConfigFile{Path: filepath.Join(tempDir, "b.yaml")}},
+				{HTTP: &ConfigHTTP{URL: srv.URL, Interval: 10 * time.Millisecond}},
+			},
+		},
+	}
+
+	if err := c.Load(ctx, wg, func(_ context.Context, _ string, data map[string]interface{}) {
+		select {
+		case calls <- data:
+		default:
+		}
+	}); err != nil {
+		t.Fatalf("Configs.Load() error = %v", err)
+	}
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"a": 0, "b": 0})
+
+	// a changed response keeps the file values
+	mu.Lock()
+	version = 1
+	mu.Unlock()
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"a": 1, "b": 0})
+}
+
+// wantLoad waits for a call of the app load with want and checks the export.
+func wantLoad(t *testing.T, calls <-chan map[string]interface{}, exportPath string, want map[string]interface{}) {
+	t.Helper()
+
+	for deadline := time.After(5 * time.Second); ; {
+		select {
+		case data := <-calls:
+			if fmt.Sprint(data["app"]) != fmt.Sprint(want) {
+				continue
+			}
+
+			v, err := os.ReadFile(exportPath)
+			if err != nil {
+				t.Fatalf("Configs.Load() read export error = %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(v, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Configs.Load() export = %v, want %v", got, want)
+			}
+
+			return
+		case <-deadline:
+			t.Fatalf("Configs.Load() no call with %v", want)
+		}
+	}
+}
+
+func TestConfigs_LoadDynamicHTTP(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		version     = "v1"
+		failing     = false
+		notModified = 0
+		revision    = 0
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		etag := `"` + version + "-" + strconv.Itoa(revision) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"server": {"version": "` + version + `"}}`))
+	}))
+	defer srv.Close()
+
+	tempDir := t.TempDir()
+	exportPath := filepath.Join(tempDir, "http.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	c := Configs{
+		{
+			Name:   "app",
+			Export: exportPath,
+			Dynamics: []ConfigDynamic{
+				{
+					HTTP: &ConfigHTTP{
+						URL:        srv.URL,
+						InnerPath:  "server",
+						Interval:   10 * time.Millisecond,
+						BackoffMax: 20 * time.Millisecond,
+					},
+				},
+			},
+		},
+	}
+
+	if err := c.Load(ctx, wg, func(_ context.Context, _ string, data map[string]interface{}) {
+		calls <- data
+	}); err != nil {
+		t.Fatalf("Configs.Load() error = %v", err)
+	}
+
+	wantExport := func(version string) {
+		t.Helper()
+
+		v, err := os.ReadFile(exportPath)
+		if err != nil {
+			t.Fatalf("Configs.Load() read export error = %v", err)
+		}
+
+		want := "{\n  \"version\": \"" + version + "\"\n}\n"
+		if string(v) != want {
+			t.Errorf("Configs.Load() content = \n%q\n, want \n%q\n", string(v), want)
+		}
+	}
+
+	wantCall := func(version string) {
+		t.Helper()
+
+		select {
+		case data := <-calls:
+			if got := data["app"].(map[string]interface{})["version"]; got != version {
+				t.Fatalf("Configs.Load() version = %v, want %v", got, version)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Configs.Load() no call for version %v", version)
+		}
+
+		wantExport(version)
+	}
+
+	wantCall("v1")
+
+	// unchanged content is answered with 304 and not reloaded
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	if notModified == 0 {
+		t.Errorf("Configs.Load() no conditional request made")
+	}
+	failing = true
+	mu.Unlock()
+
+	// failures keep the last good value
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case data := <-calls:
+		t.Fatalf("Configs.Load() unexpected call = %v", data)
+	default:
+	}
+
+	wantExport("v1")
+
+	mu.Lock()
+	failing = false
+	version = "v2"
+	mu.Unlock()
+
+	wantCall("v2")
+
+	// a new etag with the same body is not reloaded but sent on the next polls
+	mu.Lock()
+	revision = 1
+	notModified = 0
+	mu.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	if notModified == 0 {
+		t.Errorf("Configs.Load() new etag not used in conditional request")
+	}
+	mu.Unlock()
+
+	select {
+	case data := <-calls:
+		t.Fatalf("Configs.Load() unexpected call = %v", data)
+	default:
+	}
+}
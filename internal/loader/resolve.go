@@ -0,0 +1,243 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Reference prefixes for inline secrets in config values.
+const (
+	refVault = "vault:"
+	refFile  = "file:"
+	refEnv   = "env:"
+)
+
+// Resolver replaces string values that reference a secret with the secret:
+//
+//   - vault:secret/app#password reads the password key of the KVv2 secret
+//     app in the secret mount.
+//   - file:/run/secrets/x reads the file, trailing newlines are trimmed.
+//   - env:NAME reads the environment variable.
+//
+// A leading backslash keeps the value as is, \env:NAME is the literal env:NAME.
+type Resolver struct {
+	cl *clients
+}
+
+// NewResolver returns a Resolver with its own loader clients.
+func NewResolver() *Resolver {
+	return &Resolver{cl: newClients()}
+}
+
+// Resolve resolves references in place, v must be a pointer. Structs, maps,
+// slices and interfaces are walked; unexported fields are skipped.
+func (r *Resolver) Resolve(ctx context.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("resolve requires a non nil pointer, got %T", v)
+	}
+
+	return r.resolveValue(ctx, rv, "", map[uintptr]struct{}{})
+}
+
+// ResolveData returns a resolved copy of data, data itself is not changed so
+// it can be resolved again after a dynamic change.
+func (r *Resolver) ResolveData(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+	v, _ := copyValue(data).(map[string]interface{})
+	if v == nil {
+		return nil, nil
+	}
+
+	if err := r.Resolve(ctx, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// resolve resolves the references of the loaded data when Resolve is set.
+func (c *Config) resolve(ctx context.Context, to *Data) error {
+	if c.resolver == nil {
+		return nil
+	}
+
+	m, err := c.resolver.ResolveData(ctx, to.Map)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", c.Name, err)
+	}
+
+	hold, err := c.resolver.ResolveData(ctx, to.Hold)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", c.Name, err)
+	}
+
+	to.Map, to.Hold = m, hold
+
+	return nil
+}
+
+func (r *Resolver) resolveValue(ctx context.Context, v reflect.Value, name string, seen map[uintptr]struct{}) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+
+		resolved, err := r.resolveString(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimPrefix(name, "."), err)
+		}
+
+		v.SetString(resolved)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		// skip cycles
+		if _, ok := seen[v.Pointer()]; ok {
+			return nil
+		}
+
+		seen[v.Pointer()] = struct{}{}
+
+		return r.resolveValue(ctx, v.Elem(), name, seen)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+
+		if err := r.resolveValue(ctx, elem, name, seen); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+	case reflect.Struct:
+		t := v.Type()
+		for i := range v.NumField() {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+
+			if err := r.resolveValue(ctx, v.Field(i), name+"."+t.Field(i).Name, seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			// bytes are not text values
+			return nil
+		}
+
+		for i := range v.Len() {
+			if err := r.resolveValue(ctx, v.Index(i), fmt.Sprintf("%s[%d]", name, i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+
+			if err := r.resolveValue(ctx, elem, fmt.Sprintf("%s.%v", name, iter.Key()), seen); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+
+	return nil
+}
+
+// resolveString returns the referenced secret or s when it is not a reference.
+func (r *Resolver) resolveString(ctx context.Context, s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `\`) && isReference(s[1:]):
+		return s[1:], nil
+	case strings.HasPrefix(s, refEnv):
+		name := strings.TrimPrefix(s, refEnv)
+
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("env %s is not set", name)
+		}
+
+		return v, nil
+	case strings.HasPrefix(s, refFile):
+		data, err := os.ReadFile(strings.TrimPrefix(s, refFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read file reference: %w", err)
+		}
+
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(s, refVault):
+		return r.resolveVault(ctx, strings.TrimPrefix(s, refVault))
+	default:
+		return s, nil
+	}
+}
+
+// resolveVault reads mount/path#key from a KVv2 secret.
+func (r *Resolver) resolveVault(ctx context.Context, ref string) (string, error) {
+	secretPath, key, ok := strings.Cut(ref, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("vault reference %s requires #key", ref)
+	}
+
+	mount, secretPath, ok := strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !ok {
+		return "", fmt.Errorf("vault reference %s requires mount/path", ref)
+	}
+
+	cfg := &ConfigVault{PathPrefix: mount, Path: secretPath}
+
+	_, data, err := r.cl.vault.get(cfg).loadSecret(ctx, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve vault reference %s: %w", ref, err)
+	}
+
+	v, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault reference %s: key %s not found", ref, key)
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+func isReference(s string) bool {
+	return strings.HasPrefix(s, refVault) || strings.HasPrefix(s, refFile) || strings.HasPrefix(s, refEnv)
+}
+
+// copyValue deep copies maps and slices of loaded data.
+func copyValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			out[k] = copyValue(item)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, item := range vv {
+			out[i] = copyValue(item)
+		}
+
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,218 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TEST_RESOLVE_SECRET", "env-secret")
+
+	vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/app" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		writeVaultJSON(w, map[string]any{"data": map[string]any{
+			"data":     map[string]any{"password": "vault-secret", "port": 5432},
+			"metadata": map[string]any{"version": 1},
+		}})
+	}))
+
+	r := &Resolver{cl: newClients()}
+	r.cl.vault.clients = map[string]*vaultClient{
+		fmt.Sprintf("%+v", (&ConfigVault{}).auth()): vc,
+	}
+
+	type service struct {
+		Name    string
+		Env     map[string]any
+		Args    []string
+		Next    *service
+		private string
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{
+			name:  "struct",
+			value: &service{Name: "env:TEST_RESOLVE_SECRET", Args: []string{"-p", "file:" + secretFile}, private: "env:NOT_SET"},
+			want:  &service{Name: "env-secret", Args: []string{"-p", "file-secret"}, private: "env:NOT_SET"},
+		},
+		{
+			name: "map",
+			value: &map[string]any{
+				"password": "vault:secret/app#password",
+				"port":     "vault:secret/app#port",
+				"nested":   map[string]any{"list": []any{"env:TEST_RESOLVE_SECRET", 1}},
+				"literal":  `\env:TEST_RESOLVE_SECRET`,
+				"plain":    "environment",
+			},
+			want: &map[string]any{
+				"password": "vault-secret",
+				"port":     "5432",
+				"nested":   map[string]any{"list": []any{"env-secret", 1}},
+				"literal":  "env:TEST_RESOLVE_SECRET",
+				"plain":    "environment",
+			},
+		},
+		{
+			name:  "pointer in map",
+			value: &map[string]*service{"a": {Env: map[string]any{"TOKEN": "env:TEST_RESOLVE_SECRET"}}},
+			want:  &map[string]*service{"a": {Env: map[string]any{"TOKEN": "env-secret"}}},
+		},
+		{
+			name:    "missing env",
+			value:   &service{Name: "env:TEST_RESOLVE_NOT_SET"},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			value:   &service{Name: "file:" + filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "vault without key",
+			value:   &service{Name: "vault:secret/app"},
+			wantErr: true,
+		},
+		{
+			name:    "vault missing key",
+			value:   &service{Name: "vault:secret/app#user"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Resolve(context.Background(), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolver.Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(tt.value, tt.want) {
+				t.Errorf("Resolver.Resolve() = %v, want %v", tt.value, tt.want)
+			}
+		})
+	}
+
+	t.Run("cycle", func(t *testing.T) {
+		v := &service{Name: "env:TEST_RESOLVE_SECRET"}
+		v.Next = v
+
+		if err := r.Resolve(context.Background(), v); err != nil {
+			t.Fatalf("Resolver.Resolve() error = %v", err)
+		}
+
+		if v.Name != "env-secret" {
+			t.Errorf("Resolver.Resolve() name = %v, want env-secret", v.Name)
+		}
+	})
+}
+
+func TestResolver_ResolveData(t *testing.T) {
+	t.Setenv("TEST_RESOLVE_SECRET", "first")
+
+	r := NewResolver()
+
+	data := map[string]interface{}{
+		"app": map[string]interface{}{"token": "env:TEST_RESOLVE_SECRET"},
+	}
+
+	got, err := r.ResolveData(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Resolver.ResolveData() error = %v", err)
+	}
+
+	if v := got["app"].(map[string]interface{})["token"]; v != "first" {
+		t.Fatalf("Resolver.ResolveData() token = %v, want first", v)
+	}
+
+	// source data keeps the reference to resolve again
+	t.Setenv("TEST_RESOLVE_SECRET", "second")
+
+	got, err = r.ResolveData(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Resolver.ResolveData() error = %v", err)
+	}
+
+	if v := got["app"].(map[string]interface{})["token"]; v != "second" {
+		t.Errorf("Resolver.ResolveData() token = %v, want second", v)
+	}
+}
+
+func TestConfigs_LoadResolve(t *testing.T) {
+	t.Setenv("TEST_RESOLVE_SECRET", "secret")
+
+	tests := []struct {
+		name    string
+		resolve bool
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal by default", content: "token: env:TEST_RESOLVE_SECRET\n", want: "env:TEST_RESOLVE_SECRET"},
+		{name: "resolve", resolve: true, content: "token: env:TEST_RESOLVE_SECRET\n", want: "secret"},
+		{name: "resolve error", resolve: true, content: "token: env:TEST_RESOLVE_MISSING\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportPath := filepath.Join(t.TempDir(), "app.json")
+
+			var called map[string]interface{}
+
+			c := Configs{{
+				Name:    "app",
+				Export:  exportPath,
+				Resolve: tt.resolve,
+				Statics: []ConfigStatic{{Content: &ConfigContent{Content: tt.content}}},
+			}}
+
+			err := c.Load(context.Background(), nil, func(_ context.Context, _ string, data map[string]interface{}) {
+				called = data
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configs.Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := called["app"].(map[string]interface{})["token"]; got != tt.want {
+				t.Errorf("Configs.Load() call token = %v, want %v", got, tt.want)
+			}
+
+			// exports match the data of the call
+			v, err := os.ReadFile(exportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := "{\n  \"token\": \"" + tt.want + "\"\n}\n"; string(v) != want {
+				t.Errorf("Configs.Load() export = %q, want %q", v, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// compile returns the compiled JSON Schema of a file or inline schema.
+func (c *ConfigSchema) compile(name string) (*jsonschema.Schema, error) {
+	var (
+		doc any
+		loc string
+	)
+
+	switch {
+	case c.File != "":
+		path, err := filepath.Abs(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("schema file %s: %w", c.File, err)
+		}
+
+		data, err := loadFileRaw(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var v any
+		if err := decodeFileContent(path, data, &v); err != nil {
+			return nil, err
+		}
+
+		if doc, err = toJSONValue(v); err != nil {
+			return nil, fmt.Errorf("schema file %s: %w", c.File, err)
+		}
+
+		loc = path
+	case c.Inline != nil:
+		var err error
+		if doc, err = toJSONValue(c.Inline); err != nil {
+			return nil, fmt.Errorf("inline schema: %w", err)
+		}
+
+		loc = "turna://loads/" + name + "/schema.json"
+	default:
+		return nil, fmt.Errorf("schema requires file or inline")
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(loc, doc); err != nil {
+		return nil, fmt.Errorf("failed to add schema: %w", err)
+	}
+
+	schema, err := compiler.Compile(loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// validate checks the merged map against the schema. Raw content is not
+// validated.
+func (c *Config) validate(to *Data) error {
+	if c.schema == nil || to.Raw != nil {
+		return nil
+	}
+
+	v, err := toJSONValue(to.Map)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s for schema validation: %w", c.Name, err)
+	}
+
+	if err := c.schema.Validate(v); err != nil {
+		return fmt.Errorf("schema validation failed for %s: %w", c.Name, err)
+	}
+
+	return nil
+}
+
+// toJSONValue converts v to the generic JSON types the validator expects.
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+}
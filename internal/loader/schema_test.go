@@ -0,0 +1,196 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigs_LoadSchema(t *testing.T) {
+	tempDir := t.TempDir()
+
+	schemaFile := filepath.Join(tempDir, "schema.yaml")
+	if err := os.WriteFile(schemaFile, []byte(`type: object
+required: [port]
+properties:
+  port:
+    type: integer
+    maximum: 65535
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inline := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"port"},
+		"properties": map[string]interface{}{
+			"port": map[string]interface{}{"type": "integer"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		schema  *ConfigSchema
+		content string
+		wantErr string
+	}{
+		{
+			name:    "inline valid",
+			schema:  &ConfigSchema{Inline: inline},
+			content: "port: 8080",
+		},
+		{
+			name:    "inline invalid",
+			schema:  &ConfigSchema{Inline: inline},
+			content: "prot: 8080",
+			wantErr: "schema validation failed",
+		},
+		{
+			name:    "file invalid",
+			schema:  &ConfigSchema{File: schemaFile},
+			content: "port: 80800",
+			wantErr: "schema validation failed",
+		},
+		{
+			name:    "file valid",
+			schema:  &ConfigSchema{File: schemaFile},
+			content: "port: 8080",
+		},
+		{
+			name:    "missing schema",
+			schema:  &ConfigSchema{File: filepath.Join(tempDir, "missing.yaml")},
+			content: "port: 8080",
+			wantErr: "missing.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := filepath.Join(tempDir, tt.name+".yaml")
+
+			c := Configs{
+				{
+					Name:   "app",
+					Export: export,
+					Schema: tt.schema,
+					Statics: []ConfigStatic{
+						{Content: &ConfigContent{Content: tt.content}},
+					},
+				},
+			}
+
+			err := c.Load(context.Background(), nil, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Configs.Load() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Configs.Load() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if _, err := os.Stat(export); !os.IsNotExist(err) {
+				t.Errorf("Configs.Load() exported invalid data")
+			}
+		})
+	}
+}
+
+func TestConfigs_LoadDynamicSchema(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+	exportFile := filepath.Join(tempDir, "out.yaml")
+
+	write := func(content string) {
+		t.Helper()
+
+		if err := os.WriteFile(configFile+".tmp", []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Rename(configFile+".tmp", configFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("port: 8080\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	c := Configs{
+		{
+			Name:   "app",
+			Export: exportFile,
+			Schema: &ConfigSchema{Inline: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"port"},
+			}},
+			Dynamics: []ConfigDynamic{
+				{File: &ConfigFile{Path: configFile}},
+			},
+		},
+	}
+
+	if err := c.Load(ctx, wg, func(_ context.Context, _ string, data map[string]interface{}) {
+		calls <- data
+	}); err != nil {
+		t.Fatalf("Configs.Load() error = %v", err)
+	}
+
+	wantCall := func(port int) {
+		t.Helper()
+
+		select {
+		case data := <-calls:
+			if got := data["app"].(map[string]interface{})["port"]; got != port {
+				t.Fatalf("Configs.Load() port = %v, want %v", got, port)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Configs.Load() no call for port %v", port)
+		}
+	}
+
+	wantExport := func(want string) {
+		t.Helper()
+
+		v, err := os.ReadFile(exportFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(v) != want {
+			t.Errorf("Configs.Load() export = %q, want %q", v, want)
+		}
+	}
+
+	wantCall(8080)
+	wantExport("port: 8080\n")
+
+	// invalid value is rejected and the previous value stays
+	write("prot: 9090\n")
+
+	select {
+	case data := <-calls:
+		t.Fatalf("Configs.Load() unexpected call with %v", data)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	wantExport("port: 8080\n")
+
+	write("port: 9090\n")
+
+	wantCall(9090)
+	wantExport("port: 9090\n")
+}
@@ -3,18 +3,66 @@ package loader
 import (
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 )
 
-// vaultClient is a small wrapper over the vault KVv2 API used by loads.
+const (
+	defaultVaultInterval   = 30 * time.Second
+	defaultVaultBackoffMax = 5 * time.Minute
+)
+
+// vaultClients caches a vaultClient per auth configuration, so loads sharing
+// the same login share the token and its renewal.
+type vaultClients struct {
+	mutex   sync.Mutex
+	clients map[string]*vaultClient
+}
+
+// get returns the client for the auth configuration of cfg.
+func (c *vaultClients) get(cfg *ConfigVault) *vaultClient {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	auth := cfg.auth()
+	key := fmt.Sprintf("%+v", auth)
+
+	if c.clients == nil {
+		c.clients = make(map[string]*vaultClient)
+	}
+
+	if v, ok := c.clients[key]; ok {
+		return v
+	}
+
+	v := &vaultClient{auth: auth}
+	c.clients[key] = v
+
+	return v
+}
+
+// vaultClient is a small wrapper over the vault API used by loads.
 type vaultClient struct {
-	client          *api.Client
-	appRoleBasePath string
+	client *api.Client
+	auth   ConfigVaultAuth
+
+	// authSecret is the result of the last login, used to renew the token.
+	authSecret *api.Secret
+	mutex      sync.Mutex
+	renewing   bool
 }
 
 func (c *vaultClient) connect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	if c.client != nil {
 		return nil
 	}
@@ -26,49 +74,308 @@ func (c *vaultClient) connect() error {
 
 	c.client = client
 
-	return c.login(context.Background())
+	if err := c.login(context.Background()); err != nil {
+		c.client = nil
+
+		return err
+	}
+
+	return nil
 }
 
-func (c *vaultClient) login(ctx context.Context) error {
-	// A combination of a Role ID and Secret ID is required to log in with an
-	// AppRole. The role ID is provided by the Vault administrator.
-	roleID := os.Getenv("VAULT_ROLE_ID")
-	if roleID == "" {
-		return fmt.Errorf("no role ID was provided in VAULT_ROLE_ID env var")
+// loadSecret loads the secret described by cfg, a KVv2 secret by default or a
+// logical read/write for secret engines such as database/creds or pki/issue.
+func (c *vaultClient) loadSecret(ctx context.Context, cfg *ConfigVault) (*api.Secret, map[string]interface{}, error) {
+	if err := c.connect(); err != nil {
+		return nil, nil, err
 	}
 
-	appRoleBasePath := c.appRoleBasePath
-	if appRoleBasePath == "" {
-		appRoleBasePath = os.Getenv("VAULT_APPROLE_BASE_PATH")
+	switch strings.ToLower(cfg.Method) {
+	case "", "kv":
+		secret, err := c.client.KVv2(cfg.PathPrefix).Get(ctx, cfg.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get key %s: %w", cfg.Path, err)
+		}
+
+		return secret.Raw, secret.Data, nil
+	case "read":
+		secretPath := path.Join(cfg.PathPrefix, cfg.Path)
+
+		secret, err := c.client.Logical().ReadWithContext(ctx, secretPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", secretPath, err)
+		}
+
+		if secret == nil {
+			return nil, nil, fmt.Errorf("no secret found at %s", secretPath)
+		}
+
+		return secret, secret.Data, nil
+	case "write":
+		secretPath := path.Join(cfg.PathPrefix, cfg.Path)
+
+		secret, err := c.client.Logical().WriteWithContext(ctx, secretPath, cfg.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", secretPath, err)
+		}
+
+		if secret == nil {
+			return nil, nil, fmt.Errorf("no secret returned from %s", secretPath)
+		}
+
+		return secret, secret.Data, nil
+	default:
+		return nil, nil, fmt.Errorf("vault method %s not found", cfg.Method)
 	}
+}
 
-	if appRoleBasePath == "" {
-		appRoleBasePath = "auth/approle/login"
+// kvVersion returns the current version of a KVv2 secret from its metadata.
+func (c *vaultClient) kvVersion(ctx context.Context, mountPath, key string) (int, error) {
+	if err := c.connect(); err != nil {
+		return 0, err
 	}
 
-	secret, err := c.client.Logical().WriteWithContext(ctx, appRoleBasePath, map[string]interface{}{
-		"role_id":   roleID,
-		"secret_id": os.Getenv("VAULT_ROLE_SECRET"),
-	})
+	metadata, err := c.client.KVv2(mountPath).GetMetadata(ctx, key)
 	if err != nil {
-		return fmt.Errorf("failed to login to vault: %w", err)
+		return 0, fmt.Errorf("failed to get metadata of key %s: %w", key, err)
 	}
 
-	c.client.SetToken(secret.Auth.ClientToken)
+	return metadata.CurrentVersion, nil
+}
 
-	return nil
+// dynamicValue returns a channel with the secret data, starting with the
+// current value.
+//
+// KVv2 secrets are checked every cfg.Interval and sent again when the metadata
+// reports a new version. Secrets of the read/write methods are renewed while
+// their lease allows it and issued again before they expire; secrets without a
+// lease or expiration are re-read every cfg.Interval and sent when changed.
+//
+// The first load is made before returning and its error is returned. The
+// channel is closed when ctx is cancelled or the stop function is called.
+func (c *vaultClient) dynamicValue(ctx context.Context, wg *sync.WaitGroup, cfg *ConfigVault) (<-chan map[string]interface{}, func(), error) {
+	secret, data, err := c.loadSecret(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.keepToken(ctx, wg)
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultVaultInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// unbuffered: only the latest change matters
+	vChannel := make(chan map[string]interface{})
+
+	send := func(data map[string]interface{}) bool {
+		select {
+		case vChannel <- data:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(vChannel)
+
+		if !send(data) {
+			return
+		}
+
+		switch strings.ToLower(cfg.Method) {
+		case "", "kv":
+			c.watchKV(ctx, cfg, secret, interval, send)
+		default:
+			c.watchLease(ctx, cfg, secret, data, interval, send)
+		}
+	}()
+
+	return vChannel, cancel, nil
 }
 
-// loadMap loads a KVv2 secret as a map.
-func (c *vaultClient) loadMap(ctx context.Context, mountPath, key string) (map[string]interface{}, error) {
-	if err := c.connect(); err != nil {
-		return nil, err
+// watchKV polls the KVv2 metadata and sends the secret when its version changes.
+func (c *vaultClient) watchKV(ctx context.Context, cfg *ConfigVault, secret *api.Secret, interval time.Duration, send func(map[string]interface{}) bool) {
+	version := kvSecretVersion(secret)
+	failures := 0
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		current, err := c.kvVersion(ctx, cfg.PathPrefix, cfg.Path)
+		if err == nil && current != version {
+			var data map[string]interface{}
+			if secret, data, err = c.loadSecret(ctx, cfg); err == nil {
+				version = kvSecretVersion(secret)
+
+				if !send(data) {
+					return
+				}
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			failures++
+			wait := backoff(interval, defaultVaultBackoffMax, failures)
+
+			slog.Warn("failed to check vault secret version, keeping last value", "path", cfg.Path, "retry", wait.String(), "err", err.Error())
+
+			timer.Reset(wait)
+
+			continue
+		}
+
+		failures = 0
+
+		timer.Reset(interval)
 	}
+}
+
+// watchLease keeps a secret engine value valid; a renewable lease is renewed
+// until vault refuses it, then the secret is issued again and sent.
+func (c *vaultClient) watchLease(ctx context.Context, cfg *ConfigVault, secret *api.Secret, data map[string]interface{}, interval time.Duration, send func(map[string]interface{}) bool) {
+	failures := 0
+
+	for {
+		if secret != nil && secret.Renewable && secret.LeaseID != "" {
+			if !c.renewLease(ctx, cfg, secret) {
+				return
+			}
+		} else {
+			wait := interval
+			if secret != nil {
+				if ttl := secretTTL(secret); ttl > 0 {
+					// issue again when two thirds of the lifetime passed
+					wait = ttl * 2 / 3
+				}
+			}
+
+			if failures > 0 {
+				wait = backoff(interval, defaultVaultBackoffMax, failures)
+			}
+
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+
+				return
+			case <-timer.C:
+			}
+		}
+
+		newSecret, newData, err := c.loadSecret(ctx, cfg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			failures++
+
+			slog.Warn("failed to issue vault secret, keeping last value", "path", cfg.Path, "err", err.Error())
+
+			// retry after a backoff, the old secret is not renewed anymore
+			secret = nil
+
+			continue
+		}
+
+		failures = 0
+		secret = newSecret
+
+		if newSecret.LeaseID == "" && reflect.DeepEqual(newData, data) {
+			continue
+		}
 
-	secret, err := c.client.KVv2(mountPath).Get(ctx, key)
+		data = newData
+
+		slog.Info("vault secret issued", "path", cfg.Path, "lease_duration", newSecret.LeaseDuration)
+
+		if !send(newData) {
+			return
+		}
+	}
+}
+
+// renewLease renews the lease of secret until it cannot be renewed anymore.
+// It returns false when ctx is cancelled.
+func (c *vaultClient) renewLease(ctx context.Context, cfg *ConfigVault, secret *api.Secret) bool {
+	watcher, err := c.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret:        secret,
+		RenewBehavior: api.RenewBehaviorIgnoreErrors,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+		slog.Warn("failed to create vault lease watcher", "path", cfg.Path, "err", err.Error())
+
+		return ctx.Err() == nil
+	}
+
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				slog.Warn("vault lease renewal stopped", "path", cfg.Path, "err", err.Error())
+			}
+
+			return true
+		case renewal := <-watcher.RenewCh():
+			slog.Debug("vault lease renewed", "path", cfg.Path, "lease_duration", renewal.Secret.LeaseDuration)
+		}
+	}
+}
+
+// kvSecretVersion returns the version from the metadata of a KVv2 read.
+func kvSecretVersion(secret *api.Secret) int {
+	if secret == nil {
+		return 0
+	}
+
+	metadata, ok := secret.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	v, _ := strconv.Atoi(fmt.Sprint(metadata["version"]))
+
+	return v
+}
+
+// secretTTL returns the remaining lifetime of a secret from its lease, or from
+// the expiration unix time that engines like pki return without a lease.
+func secretTTL(secret *api.Secret) time.Duration {
+	if secret.LeaseDuration > 0 {
+		return time.Duration(secret.LeaseDuration) * time.Second
+	}
+
+	if v, ok := secret.Data["expiration"]; ok {
+		expiration, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		if err == nil {
+			return time.Until(time.Unix(expiration, 0))
+		}
 	}
 
-	return secret.Data, nil
+	return 0
 }
@@ -0,0 +1,288 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// auth returns the auth configuration, falling back to AppRole from the
+// VAULT_ROLE_ID and VAULT_ROLE_SECRET env vars when Auth is not set.
+func (c *ConfigVault) auth() ConfigVaultAuth {
+	if c.Auth != nil {
+		return *c.Auth
+	}
+
+	return ConfigVaultAuth{
+		Method:   "approle",
+		Path:     c.AppRoleBasePath,
+		RoleID:   os.Getenv("VAULT_ROLE_ID"),
+		SecretID: os.Getenv("VAULT_ROLE_SECRET"),
+	}
+}
+
+// loginPath returns the login endpoint of the auth method.
+func (a ConfigVaultAuth) loginPath(method string) string {
+	mount := a.Path
+	if mount == "" && method == "approle" {
+		mount = os.Getenv("VAULT_APPROLE_BASE_PATH")
+	}
+
+	if mount == "" {
+		mount = method
+	}
+
+	// full paths are kept as is for compatibility with app_role_base_path
+	if strings.HasPrefix(mount, "auth/") {
+		return mount
+	}
+
+	return path.Join("auth", mount, "login")
+}
+
+// login authenticates the client with the configured auth method.
+func (c *vaultClient) login(ctx context.Context) error {
+	method := strings.ToLower(c.auth.Method)
+
+	var body map[string]interface{}
+
+	switch method {
+	case "token":
+		return c.loginToken(ctx)
+	case "", "approle":
+		method = "approle"
+
+		// A combination of a Role ID and Secret ID is required to log in with an
+		// AppRole. The role ID is provided by the Vault administrator.
+		if c.auth.RoleID == "" {
+			return fmt.Errorf("no role ID was provided for vault approle auth")
+		}
+
+		secretID, err := readSecretValue(c.auth.SecretID, c.auth.SecretIDFile)
+		if err != nil {
+			return err
+		}
+
+		body = map[string]interface{}{
+			"role_id":   c.auth.RoleID,
+			"secret_id": secretID,
+		}
+	case "kubernetes", "jwt":
+		jwtFile := c.auth.JWTFile
+		if jwtFile == "" && c.auth.JWT == "" && method == "kubernetes" {
+			jwtFile = defaultKubernetesTokenFile
+		}
+
+		jwt, err := readSecretValue(c.auth.JWT, jwtFile)
+		if err != nil {
+			return err
+		}
+
+		if jwt == "" {
+			return fmt.Errorf("no jwt was provided for vault %s auth", method)
+		}
+
+		body = map[string]interface{}{
+			"role": c.auth.Role,
+			"jwt":  jwt,
+		}
+	default:
+		return fmt.Errorf("vault auth method %s not found", c.auth.Method)
+	}
+
+	secret, err := c.client.Logical().WriteWithContext(ctx, c.auth.loginPath(method), body)
+	if err != nil {
+		return fmt.Errorf("failed to login to vault: %w", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return fmt.Errorf("failed to login to vault: no auth info returned")
+	}
+
+	c.client.SetToken(secret.Auth.ClientToken)
+	c.authSecret = secret
+
+	return nil
+}
+
+// loginToken uses a token from the config, the token file or VAULT_TOKEN and
+// looks it up to learn its lifetime.
+func (c *vaultClient) loginToken(ctx context.Context) error {
+	token, err := readSecretValue(c.auth.Token, c.auth.TokenFile)
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if token == "" {
+		return fmt.Errorf("no token was provided for vault token auth")
+	}
+
+	c.client.SetToken(token)
+
+	secret, err := c.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lookup vault token: %w", err)
+	}
+
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return fmt.Errorf("failed to read vault token ttl: %w", err)
+	}
+
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return fmt.Errorf("failed to read vault token renewable: %w", err)
+	}
+
+	c.authSecret = &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   token,
+			Renewable:     renewable,
+			LeaseDuration: int(ttl.Seconds()),
+		},
+	}
+
+	return nil
+}
+
+// keepToken renews the login token in the background until ctx is cancelled
+// and logs in again when the token cannot be renewed anymore. Tokens without
+// a TTL, such as root tokens, are left as is. It is started once per client.
+func (c *vaultClient) keepToken(ctx context.Context, wg *sync.WaitGroup) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.renewing {
+		return
+	}
+
+	c.renewing = true
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		failures := 0
+
+		for {
+			if failures == 0 {
+				c.mutex.Lock()
+				secret := c.authSecret
+				c.mutex.Unlock()
+
+				if secret == nil || secret.Auth == nil || secret.Auth.LeaseDuration <= 0 {
+					return
+				}
+
+				if !c.waitToken(ctx, secret) {
+					return
+				}
+			} else {
+				wait := backoff(time.Second, defaultVaultBackoffMax, failures)
+
+				timer := time.NewTimer(wait)
+
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+
+					return
+				case <-timer.C:
+				}
+			}
+
+			c.mutex.Lock()
+			err := c.login(ctx)
+			c.mutex.Unlock()
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				failures++
+
+				slog.Warn("failed to login to vault again", "method", c.auth.Method, "err", err.Error())
+
+				continue
+			}
+
+			failures = 0
+
+			slog.Info("logged in to vault again", "method", c.auth.Method)
+		}
+	}()
+}
+
+// waitToken renews a renewable token until vault refuses it, or waits two
+// thirds of the lifetime of a non-renewable one. It returns false when ctx is
+// cancelled.
+func (c *vaultClient) waitToken(ctx context.Context, secret *api.Secret) bool {
+	if !secret.Auth.Renewable {
+		timer := time.NewTimer(time.Duration(secret.Auth.LeaseDuration) * time.Second * 2 / 3)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+
+	watcher, err := c.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret:        secret,
+		RenewBehavior: api.RenewBehaviorIgnoreErrors,
+	})
+	if err != nil {
+		slog.Warn("failed to create vault token watcher", "err", err.Error())
+
+		return ctx.Err() == nil
+	}
+
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				slog.Warn("vault token renewal stopped", "err", err.Error())
+			}
+
+			return true
+		case renewal := <-watcher.RenewCh():
+			slog.Debug("vault token renewed", "lease_duration", renewal.Secret.Auth.LeaseDuration)
+		}
+	}
+}
+
+// readSecretValue returns value, or the trimmed content of file when value is
+// empty and file is set.
+func readSecretValue(value, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
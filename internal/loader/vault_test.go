@@ -0,0 +1,384 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func newTestVaultClient(t *testing.T, handler http.Handler) *vaultClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.SetToken("test")
+
+	return &vaultClient{client: client}
+}
+
+func writeVaultJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func receiveVault(t *testing.T, ch <-chan map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("no vault value received")
+	}
+
+	return nil
+}
+
+func TestVaultClient_DynamicKV(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 1
+	)
+
+	vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			writeVaultJSON(w, map[string]any{"data": map[string]any{
+				"data":     map[string]any{"password": "pass-" + strconv.Itoa(version)},
+				"metadata": map[string]any{"version": version},
+			}})
+		case "/v1/secret/metadata/app":
+			writeVaultJSON(w, map[string]any{"data": map[string]any{"current_version": version}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	ch, stop, err := vc.dynamicValue(ctx, wg, &ConfigVault{
+		PathPrefix: "secret",
+		Path:       "app",
+		Interval:   10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("vaultClient.dynamicValue() error = %v", err)
+	}
+	defer stop()
+
+	if got := receiveVault(t, ch)["password"]; got != "pass-1" {
+		t.Fatalf("vaultClient.dynamicValue() password = %v, want pass-1", got)
+	}
+
+	mu.Lock()
+	version = 2
+	mu.Unlock()
+
+	if got := receiveVault(t, ch)["password"]; got != "pass-2" {
+		t.Fatalf("vaultClient.dynamicValue() password = %v, want pass-2", got)
+	}
+}
+
+func TestConfigDynamic_LoadVaultMerge(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 1
+	)
+
+	vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			writeVaultJSON(w, map[string]any{"data": map[string]any{
+				"data":     map[string]any{"password": "pass-" + strconv.Itoa(version)},
+				"metadata": map[string]any{"version": version},
+			}})
+		case "/v1/secret/metadata/app":
+			writeVaultJSON(w, map[string]any{"data": map[string]any{"current_version": version}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "b.yaml"), []byte("b: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	exportPath := filepath.Join(tempDir, "out", "app.json")
+
+	vault := &ConfigVault{PathPrefix: "secret", Path: "app", Interval: 10 * time.Millisecond}
+
+	cl := newClients()
+	cl.vault.get(vault).client = vc.client
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	calls := make(chan map[string]interface{}, 10)
+
+	config := &Config{Name: "app", Export: exportPath}
+	state := newDynamicData(Data{}, 2)
+
+	for i, dynamic := range []ConfigDynamic{
+		{File: &ConfigFile{Path: filepath.Join(tempDir, "b.yaml")}},
+		{Vault: vault},
+	} {
+		waitCtx, err := dynamic.load(ctx, wg, state.source(i), cl, config, func(_ context.Context, _ string, data map[string]interface{}) {
+			select {
+			case calls <- data:
+			default:
+			}
+		})
+		if err != nil {
+			t.Fatalf("ConfigDynamic.load() error = %v", err)
+		}
+
+		<-waitCtx.Done()
+	}
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"b": 0, "password": "pass-1"})
+
+	// a new secret version keeps the file values
+	mu.Lock()
+	version = 2
+	mu.Unlock()
+
+	wantLoad(t, calls, exportPath, map[string]interface{}{"b": 0, "password": "pass-2"})
+}
+
+func TestVaultClient_DynamicLease(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		issued = 0
+	)
+
+	vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/v1/database/creds/app" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		issued++
+
+		writeVaultJSON(w, map[string]any{
+			"lease_id":       "database/creds/app/" + strconv.Itoa(issued),
+			"lease_duration": 1,
+			"renewable":      false,
+			"data":           map[string]any{"username": "user-" + strconv.Itoa(issued)},
+		})
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	ch, stop, err := vc.dynamicValue(ctx, wg, &ConfigVault{
+		Method:     "read",
+		PathPrefix: "database",
+		Path:       "creds/app",
+	})
+	if err != nil {
+		t.Fatalf("vaultClient.dynamicValue() error = %v", err)
+	}
+	defer stop()
+
+	if got := receiveVault(t, ch)["username"]; got != "user-1" {
+		t.Fatalf("vaultClient.dynamicValue() username = %v, want user-1", got)
+	}
+
+	// issued again before the one second lease expires
+	if got := receiveVault(t, ch)["username"]; got != "user-2" {
+		t.Fatalf("vaultClient.dynamicValue() username = %v, want user-2", got)
+	}
+}
+
+func TestVaultClient_Login(t *testing.T) {
+	tempDir := t.TempDir()
+
+	jwtFile := filepath.Join(tempDir, "jwt")
+	if err := os.WriteFile(jwtFile, []byte("my-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenFile := filepath.Join(tempDir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		auth      ConfigVaultAuth
+		wantPath  string
+		wantBody  map[string]any
+		wantToken string
+		wantErr   bool
+	}{
+		{
+			name:      "approle",
+			auth:      ConfigVaultAuth{RoleID: "role", SecretID: "secret"},
+			wantPath:  "/v1/auth/approle/login",
+			wantBody:  map[string]any{"role_id": "role", "secret_id": "secret"},
+			wantToken: "login-token",
+		},
+		{
+			name:      "kubernetes with custom mount",
+			auth:      ConfigVaultAuth{Method: "kubernetes", Path: "k8s-prod", Role: "app", JWTFile: jwtFile},
+			wantPath:  "/v1/auth/k8s-prod/login",
+			wantBody:  map[string]any{"role": "app", "jwt": "my-jwt"},
+			wantToken: "login-token",
+		},
+		{
+			name:      "jwt",
+			auth:      ConfigVaultAuth{Method: "jwt", Role: "ci", JWT: "inline-jwt"},
+			wantPath:  "/v1/auth/jwt/login",
+			wantBody:  map[string]any{"role": "ci", "jwt": "inline-jwt"},
+			wantToken: "login-token",
+		},
+		{
+			name:      "token file",
+			auth:      ConfigVaultAuth{Method: "token", TokenFile: tokenFile},
+			wantPath:  "/v1/auth/token/lookup-self",
+			wantToken: "file-token",
+		},
+		{
+			name:    "jwt missing",
+			auth:    ConfigVaultAuth{Method: "jwt", Role: "ci"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown method",
+			auth:    ConfigVaultAuth{Method: "ldap"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				gotPath string
+				gotBody map[string]any
+			)
+
+			vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&gotBody)
+
+				if r.URL.Path == "/v1/auth/token/lookup-self" {
+					writeVaultJSON(w, map[string]any{"data": map[string]any{"ttl": 3600, "renewable": true}})
+
+					return
+				}
+
+				writeVaultJSON(w, map[string]any{"auth": map[string]any{
+					"client_token":   "login-token",
+					"lease_duration": 3600,
+					"renewable":      true,
+				}})
+			}))
+			vc.auth = tt.auth
+
+			err := vc.login(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vaultClient.login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if gotPath != tt.wantPath {
+				t.Errorf("vaultClient.login() path = %v, want %v", gotPath, tt.wantPath)
+			}
+
+			if tt.wantBody != nil && !reflect.DeepEqual(gotBody, tt.wantBody) {
+				t.Errorf("vaultClient.login() body = %v, want %v", gotBody, tt.wantBody)
+			}
+
+			if got := vc.client.Token(); got != tt.wantToken {
+				t.Errorf("vaultClient.login() token = %v, want %v", got, tt.wantToken)
+			}
+
+			if vc.authSecret == nil || vc.authSecret.Auth.LeaseDuration != 3600 {
+				t.Errorf("vaultClient.login() auth secret = %+v, want lease 3600", vc.authSecret)
+			}
+		})
+	}
+}
+
+func TestVaultClient_KeepToken(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		logins = 0
+	)
+
+	vc := newTestVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		logins++
+
+		writeVaultJSON(w, map[string]any{"auth": map[string]any{
+			"client_token":   "token-" + strconv.Itoa(logins),
+			"lease_duration": 1,
+			"renewable":      false,
+		}})
+	}))
+	vc.auth = ConfigVaultAuth{RoleID: "role"}
+
+	if err := vc.login(context.Background()); err != nil {
+		t.Fatalf("vaultClient.login() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	vc.keepToken(ctx, wg)
+
+	// a non-renewable token logs in again before it expires
+	deadline := time.Now().Add(5 * time.Second)
+	for vc.client.Token() == "token-1" {
+		if time.Now().After(deadline) {
+			t.Fatal("vaultClient.keepToken() did not login again")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
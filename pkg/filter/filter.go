@@ -19,6 +19,8 @@ type FileFilter struct {
 	r       *os.File
 	w       *os.File
 	Filter  func([]byte) bool
+	// Format changes a line after the filter, optional.
+	Format func([]byte) []byte
 }
 
 // Start create a goroutine to listen->filter->redirect output.
@@ -119,6 +121,10 @@ func (f *FileFilter) read(buff *bufio.Reader, withErr bool) error {
 		return nil
 	}
 
+	if f.Format != nil {
+		readed = f.Format(readed)
+	}
+
 	if _, err := f.To.Write(readed); err != nil {
 		return fmt.Errorf("failed write FileFilter.To: %w", err)
 	}
@@ -0,0 +1,32 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PrefixFormat adds the prefix to each line.
+func PrefixFormat(prefix string) func([]byte) []byte {
+	return func(line []byte) []byte {
+		return append([]byte(prefix), line...)
+	}
+}
+
+// JSONFormat writes each line as a JSON slog record with service and stream
+// attributes.
+func JSONFormat(service, stream string) func([]byte) []byte {
+	return func(line []byte) []byte {
+		var buf bytes.Buffer
+
+		record := slog.NewRecord(time.Now(), slog.LevelInfo, string(bytes.TrimRight(line, "\r\n")), 0)
+		record.AddAttrs(slog.String("service", service), slog.String("stream", stream))
+
+		if err := slog.NewJSONHandler(&buf, nil).Handle(context.Background(), record); err != nil {
+			return line
+		}
+
+		return buf.Bytes()
+	}
+}
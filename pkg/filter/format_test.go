@@ -0,0 +1,31 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrefixFormat(t *testing.T) {
+	if got := PrefixFormat("[app] ")([]byte("hello\n")); string(got) != "[app] hello\n" {
+		t.Errorf("PrefixFormat() = %q", got)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	got := JSONFormat("app", "stderr")([]byte("hello world\n"))
+
+	if got[len(got)-1] != '\n' {
+		t.Errorf("JSONFormat() = %q, want a line", got)
+	}
+
+	var v map[string]any
+	if err := json.Unmarshal(got, &v); err != nil {
+		t.Fatalf("JSONFormat() = %q, %v", got, err)
+	}
+
+	for key, want := range map[string]string{"msg": "hello world", "service": "app", "stream": "stderr", "level": "INFO"} {
+		if v[key] != want {
+			t.Errorf("JSONFormat() %s = %v, want %v", key, v[key], want)
+		}
+	}
+}
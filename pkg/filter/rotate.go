@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RotateWriter writes to a file and rotates it by size, the file is renamed
+// to file.1 and older files are shifted up to MaxBackups.
+//
+// Errors are logged and the write is dropped, a log file never blocks the
+// process output.
+type RotateWriter struct {
+	Path string
+	// MaxSize of the file in bytes, 0 never rotates.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep, default is 1.
+	MaxBackups int
+
+	f      *os.File
+	size   int64
+	failed bool
+	mutex  sync.Mutex
+}
+
+func (r *RotateWriter) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.write(p); err != nil {
+		// log only the first error of a failure
+		if !r.failed {
+			slog.Error("failed to write log file", "file", r.Path, "err", err)
+		}
+
+		r.failed = true
+
+		return len(p), nil
+	}
+
+	r.failed = false
+
+	return len(p), nil
+}
+
+func (r *RotateWriter) write(p []byte) error {
+	if r.f != nil && r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+
+	return err
+}
+
+func (r *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	r.f = f
+	r.size = info.Size()
+
+	return nil
+}
+
+func (r *RotateWriter) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+
+	r.f = nil
+
+	backups := r.MaxBackups
+	if backups <= 0 {
+		backups = 1
+	}
+
+	for i := backups - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d", r.Path, i)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", r.Path, i+1)); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(r.Path, r.Path+".1")
+}
+
+// Close closes the file, a later write opens it again.
+func (r *RotateWriter) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+
+	err := r.f.Close()
+	r.f = nil
+
+	return err
+}
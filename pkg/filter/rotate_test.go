@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateWriter_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	w := &RotateWriter{Path: path, MaxSize: 10, MaxBackups: 2}
+	defer w.Close()
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("RotateWriter.Write() error = %v", err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		path:        "line-4\n",
+		path + ".1": "line-3\n",
+		path + ".2": "line-2\n",
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != want {
+			t.Errorf("RotateWriter.Write() %s = %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("RotateWriter.Write() kept more backups, err = %v", err)
+	}
+
+	// reopened file appends
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &RotateWriter{Path: path, MaxSize: 100}
+	defer reopened.Close()
+
+	if _, err := reopened.Write([]byte("line-5\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "line-4\nline-5\n" {
+		t.Errorf("RotateWriter.Write() reopened = %q", got)
+	}
+}
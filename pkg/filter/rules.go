@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces the redacted values.
+const Mask = "***"
+
+// Rules drops and masks lines.
+type Rules struct {
+	// Contains drops lines containing one of them.
+	Contains [][]byte
+	// Regex drops lines matching one of them.
+	Regex []*regexp.Regexp
+	// MinLevel drops JSON lines with a lower level, nil keeps all.
+	MinLevel *slog.Level
+
+	// Redact masks the values.
+	Redact [][]byte
+	// RedactRegex masks the matches, only the groups of a regex with groups.
+	RedactRegex []*regexp.Regexp
+}
+
+// Keep reports whether the line is written.
+func (r *Rules) Keep(line []byte) bool {
+	for _, v := range r.Contains {
+		if bytes.Contains(line, v) {
+			return false
+		}
+	}
+
+	for _, re := range r.Regex {
+		if re.Match(line) {
+			return false
+		}
+	}
+
+	if r.MinLevel != nil {
+		if level, ok := jsonLevel(line); ok && level < *r.MinLevel {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Rewrite returns the line with the masked values.
+func (r *Rules) Rewrite(line []byte) []byte {
+	for _, v := range r.Redact {
+		if len(v) > 0 {
+			line = bytes.ReplaceAll(line, v, []byte(Mask))
+		}
+	}
+
+	for _, re := range r.RedactRegex {
+		line = redactRegex(re, line)
+	}
+
+	return line
+}
+
+func redactRegex(re *regexp.Regexp, line []byte) []byte {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAll(line, []byte(Mask))
+	}
+
+	matches := re.FindAllSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var (
+		b    bytes.Buffer
+		last int
+	)
+
+	for _, m := range matches {
+		for i := 2; i+1 < len(m); i += 2 {
+			if m[i] < last {
+				continue
+			}
+
+			b.Write(line[last:m[i]])
+			b.WriteString(Mask)
+			last = m[i+1]
+		}
+	}
+
+	b.Write(line[last:])
+
+	return b.Bytes()
+}
+
+// levels are the names used by common loggers.
+var levels = map[string]slog.Level{
+	"trace":    slog.LevelDebug - 4,
+	"debug":    slog.LevelDebug,
+	"info":     slog.LevelInfo,
+	"notice":   slog.LevelInfo,
+	"warn":     slog.LevelWarn,
+	"warning":  slog.LevelWarn,
+	"error":    slog.LevelError,
+	"fatal":    slog.LevelError + 4,
+	"panic":    slog.LevelError + 4,
+	"critical": slog.LevelError + 4,
+}
+
+// jsonLevel returns the level of a JSON log line from the level, lvl or
+// severity field. Numbers are read as pino levels, 30 is info.
+func jsonLevel(line []byte) (slog.Level, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return 0, false
+	}
+
+	var v map[string]any
+	if err := json.Unmarshal(line, &v); err != nil {
+		return 0, false
+	}
+
+	for _, key := range []string{"level", "lvl", "severity"} {
+		switch value := v[key].(type) {
+		case string:
+			level, ok := levels[strings.ToLower(value)]
+
+			return level, ok
+		case float64:
+			// pino: 10 trace, 20 debug, 30 info, 40 warn, 50 error, 60 fatal
+			return slog.Level((value-30)/10*4) + slog.LevelInfo, true
+		}
+	}
+
+	return 0, false
+}
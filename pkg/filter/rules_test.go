@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+func TestRules_Keep(t *testing.T) {
+	warn := slog.LevelWarn
+
+	rules := &Rules{
+		Contains: [][]byte{[]byte("healthz")},
+		Regex:    []*regexp.Regexp{regexp.MustCompile(`^DEBUG `)},
+		MinLevel: &warn,
+	}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{line: "GET /healthz 200\n", want: false},
+		{line: "DEBUG connection opened\n", want: false},
+		{line: "INFO DEBUG connection opened\n", want: true},
+		{line: `{"level":"info","msg":"started"}` + "\n", want: false},
+		{line: `{"level":"WARN","msg":"slow"}` + "\n", want: true},
+		{line: `{"severity":"error","msg":"failed"}` + "\n", want: true},
+		{line: `{"level":30,"msg":"pino info"}` + "\n", want: false},
+		{line: `{"level":50,"msg":"pino error"}` + "\n", want: true},
+		{line: `{"msg":"no level"}` + "\n", want: true},
+		{line: "{not json\n", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Keep([]byte(tt.line)); got != tt.want {
+			t.Errorf("Rules.Keep(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRules_Rewrite(t *testing.T) {
+	rules := &Rules{
+		Redact: [][]byte{[]byte("s3cr3t"), nil},
+		RedactRegex: []*regexp.Regexp{
+			regexp.MustCompile(`token=(\S+)`),
+			regexp.MustCompile(`Bearer [A-Za-z0-9.]+`),
+		},
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "password is s3cr3t, again s3cr3t\n", want: "password is ***, again ***\n"},
+		{line: "GET /?token=abc&x=1 token=def\n", want: "GET /?token=*** token=***\n"},
+		{line: "Authorization: Bearer eyJ.abc\n", want: "Authorization: ***\n"},
+		{line: "nothing to hide\n", want: "nothing to hide\n"},
+	}
+
+	for _, tt := range tests {
+		if got := string(rules.Rewrite([]byte(tt.line))); got != tt.want {
+			t.Errorf("Rules.Rewrite(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	archiveNone  = "none"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// archive returns the configured archive type or detects it from the source.
+func (c *Config) archive() (string, error) {
+	switch strings.ToLower(c.Archive) {
+	case "":
+	case archiveNone:
+		return archiveNone, nil
+	case archiveTar:
+		return archiveTar, nil
+	case archiveTarGz, "tgz":
+		return archiveTarGz, nil
+	case archiveZip:
+		return archiveZip, nil
+	default:
+		return "", fmt.Errorf("fetch archive %s not supported", c.Archive)
+	}
+
+	name := c.Source
+	if u, err := url.Parse(c.Source); err == nil && u.Path != "" {
+		name = u.Path
+	}
+
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip, nil
+	default:
+		return archiveNone, nil
+	}
+}
+
+// extract unpacks the archive file to dst.
+func extract(archive, file, dst string, strip int) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+
+	switch archive {
+	case archiveZip:
+		return extractZip(file, dst, strip)
+	default:
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var r io.Reader = f
+		if archive == archiveTarGz {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return fmt.Errorf("gzip: %w", err)
+			}
+			defer gz.Close()
+
+			r = gz
+		}
+
+		return extractTar(r, dst, strip)
+	}
+}
+
+func extractTar(r io.Reader, dst string, strip int) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("tar: %w", err)
+		}
+
+		target, ok, err := entryPath(dst, hdr.Name, strip)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		mode := hdr.FileInfo().Mode()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode.Perm()|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeEntry(target, tr, mode.Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeLink(dst, target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, ok, err := entryPath(dst, hdr.Linkname, strip)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return fmt.Errorf("hard link %s target %s is stripped", hdr.Name, hdr.Linkname)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+
+			os.Remove(target)
+
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			// devices, fifos and others are not extracted
+		}
+	}
+}
+
+func extractZip(file, dst string, strip int) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		target, ok, err := entryPath(dst, zf.Name, strip)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		mode := zf.Mode()
+
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, mode.Perm()|0o700); err != nil {
+				return err
+			}
+		case mode&fs.ModeSymlink != 0:
+			link, err := readZipEntry(zf)
+			if err != nil {
+				return err
+			}
+
+			if err := writeLink(dst, target, link); err != nil {
+				return err
+			}
+		default:
+			rc, err := zf.Open()
+			if err != nil {
+				return fmt.Errorf("zip %s: %w", zf.Name, err)
+			}
+
+			perm := mode.Perm()
+			if perm == 0 {
+				perm = 0o644
+			}
+
+			err = writeEntry(target, rc, perm)
+			rc.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readZipEntry(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", fmt.Errorf("zip %s: %w", zf.Name, err)
+	}
+	defer rc.Close()
+
+	v, err := io.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("zip %s: %w", zf.Name, err)
+	}
+
+	return string(v), nil
+}
+
+// entryPath returns the extract path of an archive entry after removing strip
+// leading elements. Entries escaping dst are an error, entries with nothing
+// left after strip are skipped.
+func entryPath(dst, name string, strip int) (string, bool, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", false, fmt.Errorf("archive entry %s has an absolute path", name)
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false, fmt.Errorf("archive entry %s is outside of the destination", name)
+	}
+
+	parts := strings.Split(cleaned, "/")
+	if cleaned == "." || len(parts) <= strip {
+		return "", false, nil
+	}
+
+	parts = parts[strip:]
+
+	// writing through a symlink extracted before could leave dst
+	current := dst
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+
+		if info, err := os.Lstat(current); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", false, fmt.Errorf("archive entry %s is under the symlink %s", name, current)
+		}
+	}
+
+	return filepath.Join(dst, filepath.FromSlash(strings.Join(parts, "/"))), true, nil
+}
+
+// writeLink creates a symlink, the link must resolve inside dst.
+func writeLink(dst, target, link string) error {
+	resolved := link
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(target), link)
+	}
+
+	if rel, err := filepath.Rel(dst, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("archive link %s to %s is outside of the destination", target, link)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	os.Remove(target)
+
+	return os.Symlink(link, target)
+}
+
+func writeEntry(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	// replace instead of writing through an existing symlink
+	os.Remove(target)
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+
+	return f.Close()
+}
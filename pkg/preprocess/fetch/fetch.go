@@ -0,0 +1,241 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cacheFile holds the checksum of the archive extracted to the destination.
+	cacheFile      = ".turna-fetch"
+	defaultTimeout = 5 * time.Minute
+)
+
+type Config struct {
+	// Source is an http(s) URL or a local path.
+	Source string `cfg:"source"`
+	// Destination is the file to write or the directory to extract to.
+	Destination string `cfg:"destination"`
+	// SHA256 is the expected hex checksum of the source.
+	//  - When set, a destination with the same checksum is not fetched again.
+	SHA256 string `cfg:"sha256"`
+	// Archive is the archive type: tar, tar.gz, zip or none.
+	//  - Default is detected from the source extension.
+	Archive string `cfg:"archive"`
+	// StripComponents removes leading path elements of archive entries.
+	StripComponents int `cfg:"strip_components"`
+	// Headers are added to http requests.
+	Headers map[string]string `cfg:"headers"`
+	// Timeout of the download, default is 5m.
+	Timeout time.Duration `cfg:"timeout"`
+	// FilePerm is the octal mode of a not extracted destination, default is "0644".
+	FilePerm string `cfg:"file_perm"`
+}
+
+func (c *Config) Run(ctx context.Context) error {
+	if c.Source == "" || c.Destination == "" {
+		return errors.New("fetch source and destination are required")
+	}
+
+	archive, err := c.archive()
+	if err != nil {
+		return err
+	}
+
+	perm, err := c.filePerm()
+	if err != nil {
+		return err
+	}
+
+	want := strings.ToLower(c.SHA256)
+
+	if want != "" && c.cached(archive, want) {
+		slog.Info("fetch skipped, destination has the same checksum", "source", c.Source, "destination", c.Destination)
+
+		return nil
+	}
+
+	tmp, sum, err := c.download(ctx)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if want != "" && sum != want {
+		return fmt.Errorf("fetch %s checksum mismatch: got %s, want %s", c.Source, sum, want)
+	}
+
+	switch archive {
+	case archiveNone:
+		if err := c.writeFile(tmp, perm); err != nil {
+			return err
+		}
+	default:
+		if err := extract(archive, tmp, c.Destination, c.StripComponents); err != nil {
+			return fmt.Errorf("fetch %s extract: %w", c.Source, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(c.Destination, cacheFile), []byte(sum+"\n"), 0o644); err != nil {
+			return fmt.Errorf("fetch write cache file: %w", err)
+		}
+	}
+
+	slog.Info("fetched", "source", c.Source, "destination", c.Destination, "sha256", sum)
+
+	return nil
+}
+
+// cached reports whether the destination holds the content with the checksum.
+func (c *Config) cached(archive, want string) bool {
+	if archive == archiveNone {
+		sum, err := fileSum(c.Destination)
+
+		return err == nil && sum == want
+	}
+
+	v, err := os.ReadFile(filepath.Join(c.Destination, cacheFile))
+
+	return err == nil && strings.TrimSpace(string(v)) == want
+}
+
+// download copies the source to a temporary file and returns its path and
+// checksum.
+func (c *Config) download(ctx context.Context) (string, string, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	r, err := c.open(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	f, err := os.CreateTemp("", "turna-fetch-*")
+	if err != nil {
+		return "", "", fmt.Errorf("fetch create temp file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		os.Remove(f.Name())
+
+		return "", "", fmt.Errorf("fetch %s: %w", c.Source, err)
+	}
+
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Config) open(ctx context.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.Source, "http://") && !strings.HasPrefix(c.Source, "https://") {
+		f, err := os.Open(strings.TrimPrefix(c.Source, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("fetch open source: %w", err)
+		}
+
+		return f, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch request: %w", err)
+	}
+
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", c.Source, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", c.Source, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// filePerm parses the octal file_perm such as "0600".
+func (c *Config) filePerm() (os.FileMode, error) {
+	if c.FilePerm == "" {
+		return 0o644, nil
+	}
+
+	v, err := strconv.ParseUint(c.FilePerm, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("fetch invalid file_perm %q: %w", c.FilePerm, err)
+	}
+
+	return os.FileMode(v), nil
+}
+
+// writeFile moves the downloaded file to the destination.
+func (c *Config) writeFile(tmp string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(c.Destination), 0o755); err != nil {
+		return fmt.Errorf("fetch create directory: %w", err)
+	}
+
+	src, err := os.Open(tmp)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// write next to the destination and rename to replace it at once
+	dst, err := os.CreateTemp(filepath.Dir(c.Destination), "."+filepath.Base(c.Destination)+".*")
+	if err != nil {
+		return fmt.Errorf("fetch create file: %w", err)
+	}
+	defer os.Remove(dst.Name())
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+
+		return fmt.Errorf("fetch write file: %w", err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("fetch write file: %w", err)
+	}
+
+	if err := os.Chmod(dst.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), c.Destination)
+}
+
+func fileSum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,268 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type entry struct {
+	name string
+	body string
+	link string
+	dir  bool
+}
+
+func tarGz(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+
+		switch {
+		case e.dir:
+			hdr = &tar.Header{Name: e.name, Mode: 0o755, Typeflag: tar.TypeDir}
+		case e.link != "":
+			hdr = &tar.Header{Name: e.name, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func sum(v []byte) string {
+	h := sha256.Sum256(v)
+
+	return hex.EncodeToString(h[:])
+}
+
+func TestConfig_Run(t *testing.T) {
+	bundle := tarGz(t,
+		entry{name: "dist/", dir: true},
+		entry{name: "dist/index.html", body: "index"},
+		entry{name: "dist/assets/app.js", body: "app"},
+		entry{name: "dist/latest", link: "index.html"},
+	)
+	plugin := zipArchive(t, entry{name: "plugin/plugin.so", body: "plugin"})
+	model := []byte("model")
+
+	files := map[string][]byte{
+		"/bundle.tar.gz": bundle,
+		"/plugin.zip":    plugin,
+		"/model.bin":     model,
+		"/escape.tgz":    tarGz(t, entry{name: "../escape.txt", body: "x"}),
+		"/abs.zip":       zipArchive(t, entry{name: "/etc/passwd", body: "x"}),
+		"/link.tar.gz":   tarGz(t, entry{name: "out", link: "../../outside"}),
+		"/through.tar.gz": tarGz(t,
+			entry{name: "self", link: "."},
+			entry{name: "self/x", link: ".."},
+		),
+	}
+
+	var hits atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		v, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write(v)
+	}))
+	defer srv.Close()
+
+	localModel := filepath.Join(t.TempDir(), "model.bin")
+	if err := os.WriteFile(localModel, model, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		want     map[string]string
+		wantMode os.FileMode
+		wantErr  string
+	}{
+		{
+			name:   "tar.gz with strip",
+			config: Config{Source: srv.URL + "/bundle.tar.gz", SHA256: sum(bundle), StripComponents: 1},
+			want:   map[string]string{"index.html": "index", "assets/app.js": "app", "latest": "index"},
+		},
+		{
+			name:   "zip",
+			config: Config{Source: srv.URL + "/plugin.zip"},
+			want:   map[string]string{"plugin/plugin.so": "plugin"},
+		},
+		{
+			name:   "local file",
+			config: Config{Source: localModel, SHA256: sum(model)},
+			want:   map[string]string{"": "model"},
+		},
+		{
+			name:     "file perm",
+			config:   Config{Source: srv.URL + "/model.bin", FilePerm: "0600"},
+			want:     map[string]string{"": "model"},
+			wantMode: 0o600,
+		},
+		{
+			name:    "invalid file perm",
+			config:  Config{Source: srv.URL + "/model.bin", FilePerm: "rw-r--r--"},
+			wantErr: "invalid file_perm",
+		},
+		{
+			name:    "checksum mismatch",
+			config:  Config{Source: srv.URL + "/model.bin", SHA256: sum([]byte("other"))},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "not found",
+			config:  Config{Source: srv.URL + "/missing.bin"},
+			wantErr: "unexpected status",
+		},
+		{
+			name:    "path traversal",
+			config:  Config{Source: srv.URL + "/escape.tgz"},
+			wantErr: "outside of the destination",
+		},
+		{
+			name:    "absolute path",
+			config:  Config{Source: srv.URL + "/abs.zip"},
+			wantErr: "absolute path",
+		},
+		{
+			name:    "symlink outside",
+			config:  Config{Source: srv.URL + "/link.tar.gz"},
+			wantErr: "outside of the destination",
+		},
+		{
+			name:    "write through symlink",
+			config:  Config{Source: srv.URL + "/through.tar.gz"},
+			wantErr: "under the symlink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out")
+			tt.config.Destination = dst
+
+			err := tt.config.Run(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Config.Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Config.Run() error = %v", err)
+			}
+
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dst, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(got) != want {
+					t.Errorf("Config.Run() %s = %q, want %q", name, got, want)
+				}
+			}
+
+			if tt.wantMode != 0 {
+				info, err := os.Stat(dst)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if info.Mode().Perm() != tt.wantMode {
+					t.Errorf("Config.Run() mode = %v, want %v", info.Mode().Perm(), tt.wantMode)
+				}
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		for _, source := range []string{"/bundle.tar.gz", "/model.bin"} {
+			config := Config{
+				Source:      srv.URL + source,
+				Destination: filepath.Join(t.TempDir(), "out"),
+				SHA256:      sum(files[source]),
+			}
+
+			if err := config.Run(context.Background()); err != nil {
+				t.Fatalf("Config.Run() error = %v", err)
+			}
+
+			before := hits.Load()
+			if err := config.Run(context.Background()); err != nil {
+				t.Fatalf("Config.Run() error = %v", err)
+			}
+
+			if hits.Load() != before {
+				t.Errorf("Config.Run() %s downloaded again with the same checksum", source)
+			}
+		}
+	})
+}
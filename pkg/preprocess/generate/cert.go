@@ -0,0 +1,231 @@
+package generate
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCAValidity   = 10 * 365 * 24 * time.Hour
+	defaultLeafValidity = 365 * 24 * time.Hour
+)
+
+type Certificate struct {
+	Name string `cfg:"name"`
+	// CertFile is the path of the PEM encoded certificate.
+	CertFile string `cfg:"cert_file"`
+	// KeyFile is the path of the PEM encoded private key.
+	KeyFile string `cfg:"key_file"`
+	// CA makes a certificate authority to sign other certificates.
+	CA bool `cfg:"ca"`
+	// Issuer is the name of the CA certificate signing this one, default is
+	// self-signed.
+	Issuer string `cfg:"issuer"`
+
+	// CommonName of the subject, default is Name.
+	CommonName string `cfg:"common_name"`
+	// Organization of the subject, default is turna.
+	Organization []string `cfg:"organization"`
+	DNSNames     []string `cfg:"dns_names"`
+	IPs          []string `cfg:"ips"`
+	// Usages are server and client, default is server. Not used for a CA.
+	Usages []string `cfg:"usages"`
+	// Validity is the lifetime, default is 10 years for a CA and 1 year for
+	// others.
+	Validity time.Duration `cfg:"validity"`
+
+	// KeyType is rsa, ecdsa or ed25519, default is ecdsa.
+	KeyType string `cfg:"key_type"`
+	// Bits of an rsa key, default is 2048.
+	Bits int `cfg:"bits"`
+	// Curve of an ecdsa key is P256, P384 or P521, default is P256.
+	Curve string `cfg:"curve"`
+}
+
+type issuer struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	// root is the PEM of the top certificate of the chain.
+	root []byte
+}
+
+// generate returns the values of the certificate and the issuer to sign others
+// when it is a CA.
+func (c *Certificate) generate(issuers map[string]*issuer) (map[string]any, *issuer, error) {
+	var parent *issuer
+	if c.Issuer != "" {
+		parent = issuers[c.Issuer]
+		if parent == nil {
+			return nil, nil, fmt.Errorf("issuer %s is not a CA listed before", c.Issuer)
+		}
+	}
+
+	certPEM, err := readFile(c.CertFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		key    crypto.Signer
+		keyPEM []byte
+	)
+
+	if certPEM != nil {
+		keyPEM, err = readFile(c.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if keyPEM == nil {
+			return nil, nil, fmt.Errorf("%s exists without the key file", c.CertFile)
+		}
+
+		key, err = parseKey(keyPEM)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %w", c.KeyFile, err)
+		}
+	} else {
+		key, keyPEM, err = loadOrCreateKey(c.KeyFile, c.KeyType, c.Bits, c.Curve)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		certPEM, err = c.create(key, parent)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := writeFile(c.CertFile, certPEM, 0o644); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("parse %s: no PEM block", c.CertFile)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", c.CertFile, err)
+	}
+
+	root := certPEM
+	if parent != nil {
+		root = parent.root
+	}
+
+	values := map[string]any{
+		"cert": string(certPEM),
+		"key":  string(keyPEM),
+		"ca":   string(root),
+	}
+
+	if !c.CA {
+		return values, nil, nil
+	}
+
+	return values, &issuer{cert: cert, key: key, root: root}, nil
+}
+
+// create returns a PEM encoded certificate signed by the parent or by itself.
+func (c *Certificate) create(key crypto.Signer, parent *issuer) ([]byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("serial number: %w", err)
+	}
+
+	commonName := c.CommonName
+	if commonName == "" {
+		commonName = c.Name
+	}
+
+	organization := c.Organization
+	if len(organization) == 0 {
+		organization = []string{"turna"}
+	}
+
+	validity := c.Validity
+	if validity == 0 {
+		validity = defaultLeafValidity
+		if c.CA {
+			validity = defaultCAValidity
+		}
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: organization,
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		BasicConstraintsValid: true,
+		DNSNames:              c.DNSNames,
+	}
+
+	for _, v := range c.IPs {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip %q", v)
+		}
+
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	if c.CA {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		if _, ok := key.(*rsa.PrivateKey); ok {
+			template.KeyUsage |= x509.KeyUsageKeyEncipherment
+		}
+
+		usages := c.Usages
+		if len(usages) == 0 {
+			usages = []string{"server"}
+		}
+
+		for _, usage := range usages {
+			switch strings.ToLower(usage) {
+			case "server":
+				template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+			case "client":
+				template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+			default:
+				return nil, fmt.Errorf("unknown usage %q", usage)
+			}
+		}
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, key.Public(), signerKey)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if certPEM == nil {
+		return nil, errors.New("encode certificate")
+	}
+
+	return certPEM, nil
+}
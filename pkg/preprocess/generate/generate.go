@@ -0,0 +1,190 @@
+package generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+const defaultName = "generate"
+
+type Config struct {
+	// Name of the generated values in the loaded data, default is generate.
+	//  - Values are reached with {{ .generate.<item name> }}.
+	Name string `cfg:"name"`
+	// Secrets are random passwords and tokens.
+	Secrets []Secret `cfg:"secrets"`
+	// Keys are private keys.
+	Keys []Key `cfg:"keys"`
+	// Certificates are CA and leaf certificates, an issuer must be listed
+	// before the certificates it signs.
+	Certificates []Certificate `cfg:"certificates"`
+}
+
+// generated holds the values of all generate modules to add them again after
+// the loaded data changes.
+var (
+	generated   = make(map[string]map[string]any)
+	generatedMu sync.Mutex
+)
+
+func (c *Config) Run(ctx context.Context) error {
+	values := make(map[string]any)
+
+	add := func(name string, v any) error {
+		if name == "" {
+			return errors.New("generate name is required")
+		}
+
+		if _, ok := values[name]; ok {
+			return fmt.Errorf("generate %s is already defined", name)
+		}
+
+		values[name] = v
+
+		return nil
+	}
+
+	for i := range c.Secrets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v, err := c.Secrets[i].generate()
+		if err != nil {
+			return fmt.Errorf("generate secret %s: %w", c.Secrets[i].Name, err)
+		}
+
+		if err := add(c.Secrets[i].Name, v); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v, err := c.Keys[i].generate()
+		if err != nil {
+			return fmt.Errorf("generate key %s: %w", c.Keys[i].Name, err)
+		}
+
+		if err := add(c.Keys[i].Name, v); err != nil {
+			return err
+		}
+	}
+
+	issuers := make(map[string]*issuer)
+
+	for i := range c.Certificates {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v, ca, err := c.Certificates[i].generate(issuers)
+		if err != nil {
+			return fmt.Errorf("generate certificate %s: %w", c.Certificates[i].Name, err)
+		}
+
+		if err := add(c.Certificates[i].Name, v); err != nil {
+			return err
+		}
+
+		if ca != nil {
+			issuers[c.Certificates[i].Name] = ca
+		}
+	}
+
+	name := c.Name
+	if name == "" {
+		name = defaultName
+	}
+
+	generatedMu.Lock()
+	if generated[name] == nil {
+		generated[name] = make(map[string]any)
+	}
+
+	maps.Copy(generated[name], values)
+
+	data := maps.Clone(render.Data)
+	if data == nil {
+		data = make(map[string]any)
+	}
+
+	data[name] = maps.Clone(generated[name])
+	render.Data = data
+	generatedMu.Unlock()
+
+	return nil
+}
+
+// SetData sets render.Data to the loaded data with the generated values. It
+// holds the lock of Run, so a reload does not drop values of a running
+// generate.
+func SetData(data map[string]any) {
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	for name, values := range generated {
+		data[name] = maps.Clone(values)
+	}
+
+	render.Data = data
+}
+
+// readFile returns the content of an existing file, nil if it does not exist.
+func readFile(name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	v, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return v, err
+}
+
+// writeFile creates a new file with the parent directories, an existing file
+// is never replaced.
+func writeFile(name string, content []byte, perm os.FileMode) error {
+	if name == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(name)
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+
+		return err
+	}
+
+	slog.Info("generated", "file", name)
+
+	return nil
+}
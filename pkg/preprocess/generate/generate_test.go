@@ -0,0 +1,279 @@
+package generate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+func TestSecret_generate(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret Secret
+		want   string
+	}{
+		{name: "default", secret: Secret{}, want: `^[A-Za-z0-9]{32}$`},
+		{name: "hex", secret: Secret{Format: "hex", Length: 64}, want: `^[0-9a-f]{64}$`},
+		{name: "charset", secret: Secret{Charset: "ab", Length: 10}, want: `^[ab]{10}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.secret.generate()
+			if err != nil {
+				t.Fatalf("Secret.generate() error = %v", err)
+			}
+
+			if !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("Secret.generate() = %q, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Secret{Format: "unknown"}).generate(); err == nil {
+		t.Error("Secret.generate() unknown format, want error")
+	}
+}
+
+func TestConfig_Run(t *testing.T) {
+	render.Data = map[string]any{"app": "turna"}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	dir := t.TempDir()
+
+	config := Config{
+		Name: "gen",
+		Secrets: []Secret{
+			{Name: "db_password", Path: filepath.Join(dir, "secrets/db_password")},
+		},
+		Keys: []Key{
+			{Name: "jwt", Path: filepath.Join(dir, "jwt.key"), PublicPath: filepath.Join(dir, "jwt.pub"), Type: "rsa"},
+			{Name: "sign", Path: filepath.Join(dir, "sign.key"), Type: "ed25519"},
+		},
+		Certificates: []Certificate{
+			{Name: "ca", CertFile: filepath.Join(dir, "ca.crt"), KeyFile: filepath.Join(dir, "ca.key"), CA: true},
+			{
+				Name: "server", CertFile: filepath.Join(dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key"),
+				Issuer: "ca", DNSNames: []string{"api.local"}, IPs: []string{"127.0.0.1"}, Usages: []string{"server", "client"},
+			},
+		},
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	values, ok := render.Data["gen"].(map[string]any)
+	if !ok {
+		t.Fatalf("Config.Run() render.Data = %v", render.Data)
+	}
+
+	if render.Data["app"] != "turna" {
+		t.Errorf("Config.Run() removed loaded data = %v", render.Data)
+	}
+
+	password, err := os.ReadFile(filepath.Join(dir, "secrets/db_password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values["db_password"] != string(password) {
+		t.Errorf("Config.Run() db_password = %v, file %q", values["db_password"], password)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "jwt.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Config.Run() key mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	jwt, err := parseKey([]byte(values["jwt"].(map[string]any)["private"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := jwt.(*rsa.PrivateKey); !ok {
+		t.Errorf("Config.Run() jwt key = %T, want rsa", jwt)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "jwt.pub")); err != nil {
+		t.Errorf("Config.Run() public key: %v", err)
+	}
+
+	sign, err := parseKey([]byte(values["sign"].(map[string]any)["private"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := sign.(ed25519.PrivateKey); !ok {
+		t.Errorf("Config.Run() sign key = %T, want ed25519", sign)
+	}
+
+	// server certificate is signed by the ca
+	server := values["server"].(map[string]any)
+	if server["ca"] != values["ca"].(map[string]any)["cert"] {
+		t.Error("Config.Run() server ca is not the ca certificate")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(server["ca"].(string))) {
+		t.Fatal("append ca")
+	}
+
+	pair, err := tls.X509KeyPair([]byte(server["cert"].(string)), []byte(server["key"].(string)))
+	if err != nil {
+		t.Fatalf("Config.Run() server key pair: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:   "api.local",
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("Config.Run() verify server: %v", err)
+	}
+
+	// second run keeps the files and values
+	render.Data = map[string]any{}
+
+	again := config
+	if err := again.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() again error = %v", err)
+	}
+
+	valuesAgain := render.Data["gen"].(map[string]any)
+	for _, name := range []string{"db_password", "jwt", "sign", "ca", "server"} {
+		if !equalValue(values[name], valuesAgain[name]) {
+			t.Errorf("Config.Run() again %s changed", name)
+		}
+	}
+
+	SetData(map[string]any{"loaded": "value"})
+
+	if !equalValue(render.Data["gen"].(map[string]any)["db_password"], values["db_password"]) || render.Data["loaded"] != "value" {
+		t.Errorf("SetData() = %v", render.Data)
+	}
+}
+
+func TestSetData(t *testing.T) {
+	render.Data = map[string]any{}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	config := Config{
+		Name:    "race",
+		Secrets: []Secret{{Name: "token", Path: filepath.Join(t.TempDir(), "token")}},
+	}
+
+	// a dynamic reload while generate runs
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for range 100 {
+			SetData(map[string]any{"loaded": "value"})
+		}
+	}()
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	<-done
+
+	SetData(map[string]any{"loaded": "value"})
+
+	if _, ok := render.Data["race"].(map[string]any)["token"]; !ok || render.Data["loaded"] != "value" {
+		t.Errorf("SetData() = %v", render.Data)
+	}
+}
+
+func TestConfig_RunErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "unknown issuer",
+			config: Config{Certificates: []Certificate{{Name: "server", Issuer: "ca"}}},
+		},
+		{
+			name: "issuer not a ca",
+			config: Config{Certificates: []Certificate{
+				{Name: "root"},
+				{Name: "server", Issuer: "root"},
+			}},
+		},
+		{
+			name: "duplicate name",
+			config: Config{
+				Secrets: []Secret{{Name: "x"}},
+				Keys:    []Key{{Name: "x"}},
+			},
+		},
+		{
+			name: "certificate without key",
+			config: Config{Certificates: []Certificate{
+				{Name: "server", CertFile: writeTemp(t, dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key")},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Run(context.Background()); err == nil {
+				t.Error("Config.Run() want error")
+			}
+		})
+	}
+}
+
+func writeTemp(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE"}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func equalValue(a, b any) bool {
+	am, aok := a.(map[string]any)
+	bm, bok := b.(map[string]any)
+
+	if !aok || !bok {
+		return a == b
+	}
+
+	if len(am) != len(bm) {
+		return false
+	}
+
+	for k, v := range am {
+		if bm[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
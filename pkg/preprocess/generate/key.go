@@ -0,0 +1,162 @@
+package generate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultRSABits = 2048
+
+type Key struct {
+	Name string `cfg:"name"`
+	// Path of the PEM encoded PKCS #8 private key.
+	Path string `cfg:"path"`
+	// PublicPath writes the PEM encoded public key, optional.
+	PublicPath string `cfg:"public_path"`
+	// Type is rsa, ecdsa or ed25519, default is ecdsa.
+	Type string `cfg:"type"`
+	// Bits of an rsa key, default is 2048.
+	Bits int `cfg:"bits"`
+	// Curve of an ecdsa key is P256, P384 or P521, default is P256.
+	Curve string `cfg:"curve"`
+}
+
+func (k *Key) generate() (map[string]any, error) {
+	key, keyPEM, err := loadOrCreateKey(k.Path, k.Type, k.Bits, k.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	publicPEM, err := encodePublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := readFile(k.PublicPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		if err := writeFile(k.PublicPath, publicPEM, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]any{
+		"private": string(keyPEM),
+		"public":  string(publicPEM),
+	}, nil
+}
+
+// loadOrCreateKey reads the private key at path or creates a new one.
+func loadOrCreateKey(path, typ string, bits int, curve string) (crypto.Signer, []byte, error) {
+	v, err := readFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if v != nil {
+		key, err := parseKey(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+
+		return key, v, nil
+	}
+
+	key, err := newKey(typ, bits, curve)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := writeFile(path, keyPEM, 0o600); err != nil {
+		return nil, nil, err
+	}
+
+	return key, keyPEM, nil
+}
+
+func newKey(typ string, bits int, curve string) (crypto.Signer, error) {
+	switch strings.ToLower(typ) {
+	case "", "ecdsa":
+		var c elliptic.Curve
+
+		switch strings.ToUpper(strings.ReplaceAll(curve, "-", "")) {
+		case "", "P256":
+			c = elliptic.P256()
+		case "P384":
+			c = elliptic.P384()
+		case "P521":
+			c = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unknown curve %q", curve)
+		}
+
+		return ecdsa.GenerateKey(c, rand.Reader)
+	case "rsa":
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown key type %q", typ)
+	}
+}
+
+// parseKey reads a PKCS #8, PKCS #1 or SEC 1 private key.
+func parseKey(v []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(v)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key %T", key)
+		}
+
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported private key")
+}
+
+func encodePublicKey(key crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
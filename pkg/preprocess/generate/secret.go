@@ -0,0 +1,90 @@
+package generate
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const defaultSecretLength = 32
+
+var charsets = map[string]string{
+	"alphanumeric": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"hex":          "0123456789abcdef",
+	"base64":       "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",
+	"base64url":    "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_",
+	"ascii":        "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~",
+}
+
+type Secret struct {
+	Name string `cfg:"name"`
+	// Path of the secret file, without a path a new value is generated on
+	// every start.
+	Path string `cfg:"path"`
+	// Length is the number of characters, default is 32.
+	Length int `cfg:"length"`
+	// Format is alphanumeric, hex, base64, base64url or ascii, default is
+	// alphanumeric.
+	Format string `cfg:"format"`
+	// Charset is a custom set of characters, overrides Format.
+	Charset string `cfg:"charset"`
+}
+
+func (s *Secret) generate() (string, error) {
+	v, err := readFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if v != nil {
+		return strings.TrimRight(string(v), "\r\n"), nil
+	}
+
+	charset := s.Charset
+	if charset == "" {
+		format := strings.ToLower(s.Format)
+		if format == "" {
+			format = "alphanumeric"
+		}
+
+		var ok bool
+		if charset, ok = charsets[format]; !ok {
+			return "", fmt.Errorf("unknown format %q", s.Format)
+		}
+	}
+
+	length := s.Length
+	if length <= 0 {
+		length = defaultSecretLength
+	}
+
+	secret, err := randomString(charset, length)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeFile(s.Path, []byte(secret), 0o600); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// randomString returns uniformly chosen characters of the charset.
+func randomString(charset string, length int) (string, error) {
+	chars := []rune(charset)
+	limit := big.NewInt(int64(len(chars)))
+
+	var b strings.Builder
+	for range length {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteRune(chars[n.Int64()])
+	}
+
+	return b.String(), nil
+}
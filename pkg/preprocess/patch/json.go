@@ -0,0 +1,101 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encodeJSON writes the node as indented JSON keeping the key order.
+func encodeJSON(root *yaml.Node, indent int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := writeJSON(&buf, root, strings.Repeat(" ", indent), ""); err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, n *yaml.Node, indent, prefix string) error {
+	n = resolve(n)
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("{}")
+
+			return nil
+		}
+
+		buf.WriteString("{\n")
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(prefix + indent)
+			buf.Write(key)
+			buf.WriteString(": ")
+
+			if err := writeJSON(buf, n.Content[i+1], indent, prefix+indent); err != nil {
+				return err
+			}
+
+			if i+2 < len(n.Content) {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteByte('\n')
+		}
+
+		buf.WriteString(prefix + "}")
+	case yaml.SequenceNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("[]")
+
+			return nil
+		}
+
+		buf.WriteString("[\n")
+
+		for i, item := range n.Content {
+			buf.WriteString(prefix + indent)
+
+			if err := writeJSON(buf, item, indent, prefix+indent); err != nil {
+				return err
+			}
+
+			if i+1 < len(n.Content) {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteByte('\n')
+		}
+
+		buf.WriteString(prefix + "]")
+	case yaml.ScalarNode:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("value %s: %w", n.Value, err)
+		}
+
+		buf.Write(data)
+	default:
+		return fmt.Errorf("unsupported yaml node kind %d", n.Kind)
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+package patch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rakunlabs/turna/pkg/render"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	codecYAML = "YAML"
+	codecJSON = "JSON"
+	codecTOML = "TOML"
+)
+
+type Config struct {
+	// File is the YAML, JSON or TOML file to patch.
+	File string `cfg:"file"`
+	// Output is the file to write, default is File.
+	Output string `cfg:"output"`
+	// Codec is YAML, JSON or TOML, default is detected from the extension.
+	Codec string `cfg:"codec"`
+	// Operations are applied in order.
+	Operations []Operation `cfg:"operations"`
+}
+
+type Operation struct {
+	// Op is set, delete or a JSON Patch operation: add, remove, replace,
+	// move, copy and test.
+	//  - set creates missing maps on the path, delete ignores a missing path.
+	Op string `cfg:"op"`
+	// Path is a JSON Pointer like /server/port or a dotted path like
+	// server.port or servers[0].host.
+	Path string `cfg:"path"`
+	// From is the source path of move and copy.
+	From string `cfg:"from"`
+	// Value of set, add, replace and test.
+	Value any `cfg:"value"`
+	// Template renders a string value with the loaded data and reads the
+	// result as YAML, so "{{ .port }}" becomes a number.
+	Template bool `cfg:"template"`
+}
+
+func (c *Config) Run(_ context.Context) error {
+	if c.File == "" {
+		return errors.New("patch file is required")
+	}
+
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(c.File)
+	if err != nil {
+		return fmt.Errorf("patch read file: %w", err)
+	}
+
+	root, err := decode(codec, content)
+	if err != nil {
+		return fmt.Errorf("patch decode %s: %w", c.File, err)
+	}
+
+	for i := range c.Operations {
+		if err := c.Operations[i].apply(root); err != nil {
+			return fmt.Errorf("patch %s operation %d %s: %w", c.File, i, c.Operations[i].Op, err)
+		}
+	}
+
+	result, err := encode(codec, root, content)
+	if err != nil {
+		return fmt.Errorf("patch encode %s: %w", c.File, err)
+	}
+
+	output := c.Output
+	if output == "" {
+		output = c.File
+	}
+
+	if current, err := os.ReadFile(output); err == nil && bytes.Equal(current, result) {
+		slog.Debug("patch not changed", "file", output)
+
+		return nil
+	}
+
+	if err := writeFile(c.File, output, result); err != nil {
+		return fmt.Errorf("patch write file: %w", err)
+	}
+
+	slog.Info("patched", "file", output, "operations", len(c.Operations))
+
+	return nil
+}
+
+func (c *Config) codec() (string, error) {
+	codec := strings.ToUpper(c.Codec)
+	if codec == "" {
+		switch strings.ToLower(filepath.Ext(c.File)) {
+		case ".yaml", ".yml":
+			codec = codecYAML
+		case ".json":
+			codec = codecJSON
+		case ".toml":
+			codec = codecTOML
+		}
+	}
+
+	switch codec {
+	case codecYAML, "YML":
+		return codecYAML, nil
+	case codecJSON, codecTOML:
+		return codec, nil
+	default:
+		return "", fmt.Errorf("patch codec of %s not supported", c.File)
+	}
+}
+
+func (o *Operation) apply(root *yaml.Node) error {
+	path, err := parsePath(o.Path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(o.Op) {
+	case "set":
+		value, err := o.value()
+		if err != nil {
+			return err
+		}
+
+		return add(root, path, value, true, false)
+	case "add":
+		value, err := o.value()
+		if err != nil {
+			return err
+		}
+
+		return add(root, path, value, false, false)
+	case "replace":
+		value, err := o.value()
+		if err != nil {
+			return err
+		}
+
+		return add(root, path, value, false, true)
+	case "delete":
+		if _, err := remove(root, path); err != nil && !errors.Is(err, errNotFound) {
+			return err
+		}
+
+		return nil
+	case "remove":
+		_, err := remove(root, path)
+
+		return err
+	case "move", "copy":
+		from, err := parsePath(o.From)
+		if err != nil {
+			return err
+		}
+
+		var value *yaml.Node
+		if strings.EqualFold(o.Op, "move") {
+			value, err = remove(root, from)
+		} else {
+			value, err = get(root, from)
+			if err == nil {
+				value = deepCopy(value)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return add(root, path, value, false, false)
+	case "test":
+		value, err := o.value()
+		if err != nil {
+			return err
+		}
+
+		current, err := get(root, path)
+		if err != nil {
+			return err
+		}
+
+		var want, got any
+		if err := value.Decode(&want); err != nil {
+			return err
+		}
+
+		if err := current.Decode(&got); err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Errorf("%s is %v, want %v", o.Path, got, want)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q", o.Op)
+	}
+}
+
+// value returns the operation value as a node.
+func (o *Operation) value() (*yaml.Node, error) {
+	if s, ok := o.Value.(string); ok && o.Template {
+		rendered, err := render.ExecuteWithData(s, render.Data)
+		if err != nil {
+			return nil, fmt.Errorf("render value: %w", err)
+		}
+
+		doc := &yaml.Node{}
+		if err := yaml.Unmarshal(rendered, doc); err != nil {
+			return nil, fmt.Errorf("read rendered value as yaml: %w", err)
+		}
+
+		if len(doc.Content) == 0 {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+
+		return doc.Content[0], nil
+	}
+
+	n := &yaml.Node{}
+	if err := n.Encode(o.Value); err != nil {
+		return nil, fmt.Errorf("encode value: %w", err)
+	}
+
+	return n, nil
+}
+
+func deepCopy(n *yaml.Node) *yaml.Node {
+	v := *n
+
+	if n.Content != nil {
+		v.Content = make([]*yaml.Node, len(n.Content))
+		for i, c := range n.Content {
+			v.Content[i] = deepCopy(c)
+		}
+	}
+
+	return &v
+}
+
+// decode returns the root node of the content, TOML is converted to a node
+// without comments.
+func decode(codec string, content []byte) (*yaml.Node, error) {
+	if codec == codecTOML {
+		var v map[string]any
+		if err := toml.Unmarshal(content, &v); err != nil {
+			return nil, err
+		}
+
+		n := &yaml.Node{}
+		if err := n.Encode(v); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	root := doc.Content[0]
+
+	// keep comments above the document
+	if doc.HeadComment != "" && root.HeadComment == "" {
+		root.HeadComment = doc.HeadComment
+	}
+
+	return root, nil
+}
+
+func encode(codec string, root *yaml.Node, original []byte) ([]byte, error) {
+	switch codec {
+	case codecJSON:
+		return encodeJSON(root, detectIndent(original, 2))
+	case codecTOML:
+		var v map[string]any
+		if err := root.Decode(&v); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	default:
+		var buf bytes.Buffer
+
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(detectIndent(original, 2))
+
+		if err := enc.Encode(root); err != nil {
+			return nil, err
+		}
+
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+// detectIndent returns the indent of the first indented line.
+func detectIndent(content []byte, def int) int {
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " ")
+		if indent := len(line) - len(trimmed); indent > 0 && len(bytes.TrimSpace(trimmed)) > 0 && trimmed[0] != '#' {
+			return indent
+		}
+	}
+
+	return def
+}
+
+// writeFile replaces output at once, keeping the mode of file.
+func writeFile(file, output string, content []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), output)
+}
@@ -0,0 +1,191 @@
+package patch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "", want: nil},
+		{path: "/server/ports/0", want: []string{"server", "ports", "0"}},
+		{path: "/a~1b/c~0d", want: []string{"a/b", "c~d"}},
+		{path: "server.port", want: []string{"server", "port"}},
+		{path: "servers[0].host", want: []string{"servers", "0", "host"}},
+		{path: "matrix[1][2]", want: []string{"matrix", "1", "2"}},
+		{path: `labels.app\.kubernetes\.io/name`, want: []string{"labels", "app.kubernetes.io/name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatalf("parsePath() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Run(t *testing.T) {
+	render.Data = map[string]any{"db": map[string]any{"host": "db.local", "port": 5432}}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		operations []Operation
+		want       string
+		wantErr    string
+	}{
+		{
+			name: "yaml keeps comments",
+			file: "app.yaml",
+			content: `# app config
+server:
+  port: 8080 # http port
+  debug: true
+database:
+  host: localhost
+`,
+			operations: []Operation{
+				{Op: "set", Path: "server.port", Value: 9090},
+				{Op: "delete", Path: "server.debug"},
+				{Op: "set", Path: "database.host", Value: "{{ .db.host }}", Template: true},
+				{Op: "set", Path: "database.port", Value: "{{ .db.port }}", Template: true},
+				{Op: "set", Path: "/metrics/enabled", Value: true},
+				{Op: "delete", Path: "missing.key"},
+			},
+			want: `# app config
+server:
+  port: 9090 # http port
+database:
+  host: db.local
+  port: 5432
+metrics:
+  enabled: true
+`,
+		},
+		{
+			name: "json keeps order",
+			file: "app.json",
+			content: `{
+    "name": "app",
+    "servers": [
+        {"host": "a"},
+        {"host": "b"}
+    ],
+    "version": "1"
+}
+`,
+			operations: []Operation{
+				{Op: "replace", Path: "/servers/1/host", Value: "c"},
+				{Op: "add", Path: "/servers/-", Value: map[string]any{"host": "d"}},
+				{Op: "copy", From: "/name", Path: "/alias"},
+				{Op: "move", From: "/version", Path: "/release"},
+				{Op: "test", Path: "/servers/0/host", Value: "a"},
+				{Op: "remove", Path: "/servers/0"},
+			},
+			want: `{
+    "name": "app",
+    "servers": [
+        {
+            "host": "c"
+        },
+        {
+            "host": "d"
+        }
+    ],
+    "alias": "app",
+    "release": "1"
+}
+`,
+		},
+		{
+			name:    "toml",
+			file:    "app.toml",
+			content: "title = \"app\"\n\n[server]\nport = 8080\n",
+			operations: []Operation{
+				{Op: "set", Path: "server.port", Value: 9090},
+				{Op: "set", Path: "server.hosts", Value: []any{"a", "b"}},
+			},
+			want: "title = \"app\"\n\n[server]\n  hosts = [\"a\", \"b\"]\n  port = 9090\n",
+		},
+		{
+			name:       "test fails",
+			file:       "app.yaml",
+			content:    "version: 1\n",
+			operations: []Operation{{Op: "test", Path: "version", Value: 2}},
+			wantErr:    "version is 1, want 2",
+		},
+		{
+			name:       "remove missing",
+			file:       "app.yaml",
+			content:    "version: 1\n",
+			operations: []Operation{{Op: "remove", Path: "/name"}},
+			wantErr:    "/name: path not found",
+		},
+		{
+			name:       "replace missing",
+			file:       "app.yaml",
+			content:    "version: 1\n",
+			operations: []Operation{{Op: "replace", Path: "/name", Value: "x"}},
+			wantErr:    "/name: path not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config := Config{File: file, Operations: tt.operations}
+
+			err := config.Run(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Config.Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Config.Run() error = %v", err)
+			}
+
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Config.Run() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("Config.Run() mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
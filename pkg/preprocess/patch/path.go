@@ -0,0 +1,262 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var errNotFound = errors.New("path not found")
+
+// parsePath splits a JSON Pointer like /server/ports/0 or a dotted path like
+// server.ports[0]. In dotted paths \. is a literal dot.
+func parsePath(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(p, "/") {
+		parts := strings.Split(p[1:], "/")
+		for i, part := range parts {
+			parts[i] = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		}
+
+		return parts, nil
+	}
+
+	var (
+		tokens []string
+		token  strings.Builder
+		index  bool
+	)
+
+	flush := func() {
+		tokens = append(tokens, token.String())
+		token.Reset()
+	}
+
+	for i := 0; i < len(p); i++ {
+		switch ch := p[i]; {
+		case ch == '\\' && i+1 < len(p):
+			i++
+			token.WriteByte(p[i])
+		case ch == '.' && !index:
+			flush()
+		case ch == '[' && !index:
+			if token.Len() > 0 {
+				flush()
+			}
+
+			index = true
+		case ch == ']' && index:
+			flush()
+
+			index = false
+
+			if i+1 < len(p) && p[i+1] == '.' {
+				i++
+			}
+		default:
+			token.WriteByte(ch)
+		}
+	}
+
+	if index {
+		return nil, fmt.Errorf("path %s has an unclosed [", p)
+	}
+
+	if token.Len() > 0 || len(p) > 0 && p[len(p)-1] == '.' {
+		flush()
+	}
+
+	return tokens, nil
+}
+
+// child returns the child of a mapping or sequence node.
+func child(n *yaml.Node, token string) (*yaml.Node, error) {
+	n = resolve(n)
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == token {
+				return n.Content[i+1], nil
+			}
+		}
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(token)
+		if err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i], nil
+		}
+	}
+
+	return nil, errNotFound
+}
+
+// get returns the node at path.
+func get(root *yaml.Node, path []string) (*yaml.Node, error) {
+	n := root
+	for i, token := range path {
+		v, err := child(n, token)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatPath(path[:i+1]), err)
+		}
+
+		n = v
+	}
+
+	return n, nil
+}
+
+// parent returns the parent of path, with create missing maps are added.
+func parent(root *yaml.Node, path []string, create bool) (*yaml.Node, error) {
+	n := root
+	for i, token := range path[:len(path)-1] {
+		v, err := child(n, token)
+		if errors.Is(err, errNotFound) && create && resolve(n).Kind == yaml.MappingNode {
+			v = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			m := resolve(n)
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, v)
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatPath(path[:i+1]), err)
+		}
+
+		n = v
+	}
+
+	return resolve(n), nil
+}
+
+// add sets a mapping key or inserts into a sequence, - appends. With replace
+// the target must exist and is replaced.
+func add(root *yaml.Node, path []string, value *yaml.Node, create, replace bool) error {
+	if len(path) == 0 {
+		keepComments(root, value)
+		*root = *value
+
+		return nil
+	}
+
+	p, err := parent(root, path, create)
+	if err != nil {
+		return err
+	}
+
+	token := path[len(path)-1]
+
+	switch p.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(p.Content); i += 2 {
+			if p.Content[i].Value == token {
+				keepComments(p.Content[i+1], value)
+				p.Content[i+1] = value
+
+				return nil
+			}
+		}
+
+		if replace {
+			return fmt.Errorf("%s: %w", formatPath(path), errNotFound)
+		}
+
+		p.Content = append(p.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, value)
+
+		return nil
+	case yaml.SequenceNode:
+		if token == "-" && !replace {
+			p.Content = append(p.Content, value)
+
+			return nil
+		}
+
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i > len(p.Content) || replace && i == len(p.Content) {
+			return fmt.Errorf("%s: invalid index", formatPath(path))
+		}
+
+		if replace || create && i < len(p.Content) {
+			keepComments(p.Content[i], value)
+			p.Content[i] = value
+
+			return nil
+		}
+
+		p.Content = append(p.Content[:i], append([]*yaml.Node{value}, p.Content[i:]...)...)
+
+		return nil
+	default:
+		return fmt.Errorf("%s: parent is not a map or list", formatPath(path))
+	}
+}
+
+// remove deletes the node at path and returns it.
+func remove(root *yaml.Node, path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the root")
+	}
+
+	p, err := parent(root, path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+
+	switch p.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(p.Content); i += 2 {
+			if p.Content[i].Value == token {
+				v := p.Content[i+1]
+				p.Content = append(p.Content[:i], p.Content[i+2:]...)
+
+				return v, nil
+			}
+		}
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(token)
+		if err == nil && i >= 0 && i < len(p.Content) {
+			v := p.Content[i]
+			p.Content = append(p.Content[:i], p.Content[i+1:]...)
+
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", formatPath(path), errNotFound)
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+
+	return n
+}
+
+// keepComments moves the comments of the replaced node to the new one.
+func keepComments(from, to *yaml.Node) {
+	if to.HeadComment == "" {
+		to.HeadComment = from.HeadComment
+	}
+
+	if to.LineComment == "" {
+		to.LineComment = from.LineComment
+	}
+
+	if to.FootComment == "" {
+		to.FootComment = from.FootComment
+	}
+}
+
+func formatPath(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+
+	return b.String()
+}
@@ -4,13 +4,23 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/rakunlabs/turna/pkg/preprocess/fetch"
+	"github.com/rakunlabs/turna/pkg/preprocess/generate"
+	"github.com/rakunlabs/turna/pkg/preprocess/patch"
 	"github.com/rakunlabs/turna/pkg/preprocess/replace"
+	"github.com/rakunlabs/turna/pkg/preprocess/template"
+	"github.com/rakunlabs/turna/pkg/preprocess/wait"
 )
 
 type Configs []Config
 
 type Config struct {
-	Replace *replace.Config `cfg:"replace"`
+	Replace  *replace.Config  `cfg:"replace"`
+	Template *template.Config `cfg:"template"`
+	Fetch    *fetch.Config    `cfg:"fetch"`
+	Wait     *wait.Config     `cfg:"wait"`
+	Patch    *patch.Config    `cfg:"patch"`
+	Generate *generate.Config `cfg:"generate"`
 }
 
 type Runner interface {
@@ -22,6 +32,26 @@ func (c Config) Get() Runner {
 		return c.Replace
 	}
 
+	if c.Template != nil {
+		return c.Template
+	}
+
+	if c.Fetch != nil {
+		return c.Fetch
+	}
+
+	if c.Wait != nil {
+		return c.Wait
+	}
+
+	if c.Patch != nil {
+		return c.Patch
+	}
+
+	if c.Generate != nil {
+		return c.Generate
+	}
+
 	return nil
 }
 
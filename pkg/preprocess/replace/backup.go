@@ -0,0 +1,126 @@
+package replace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backupFile writes the original content to file.bak if it does not exist, so
+// the backup keeps the first original over restarts.
+func backupFile(file string, content []byte) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file+".bak", os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return f.Close()
+}
+
+// backupArchive is a tar.gz with the originals of the replaced files. The
+// file is created on the first Add and an existing archive is kept, so the
+// archive holds the first originals over restarts like file.bak.
+type backupArchive struct {
+	path string
+	skip bool
+
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+func newBackupArchive(path string) *backupArchive {
+	return &backupArchive{path: path}
+}
+
+func (a *backupArchive) open() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create backup archive folder: %w", err)
+	}
+
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		slog.Info("backup archive exists, keeping it", "archive", a.path)
+
+		a.skip = true
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create backup archive: %w", err)
+	}
+
+	a.file = f
+	a.gz = gzip.NewWriter(f)
+	a.tw = tar.NewWriter(a.gz)
+
+	return nil
+}
+
+// Add writes the content of file with a path relative to root.
+func (a *backupArchive) Add(root, file string, content []byte) error {
+	if a.file == nil && !a.skip {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+
+	if a.skip {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	name, err := filepath.Rel(root, file)
+	if err != nil || name == "." {
+		name = filepath.Base(file)
+	}
+
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:     filepath.ToSlash(name),
+		Mode:     int64(info.Mode().Perm()),
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+
+	if _, err := a.tw.Write(content); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+
+	return nil
+}
+
+func (a *backupArchive) Close() error {
+	if a.file == nil {
+		return nil
+	}
+
+	return errors.Join(a.tw.Close(), a.gz.Close(), a.file.Close())
+}
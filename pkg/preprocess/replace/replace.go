@@ -3,13 +3,17 @@ package replace
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/rakunlabs/turna/pkg/render"
 )
 
@@ -20,6 +24,19 @@ type Config struct {
 	// SkipDirs is the dirs to skip.
 	SkipDirs []string  `cfg:"skip_dirs"`
 	Contents []Content `cfg:"contents"`
+
+	// DryRun prints a unified diff of each file instead of writing it.
+	DryRun bool `cfg:"dry_run"`
+	// Backup writes the original of a changed file to <file>.bak, an
+	// existing backup is kept.
+	Backup bool `cfg:"backup"`
+	// BackupArchive writes the originals of changed files to a tar.gz with
+	// paths relative to Path.
+	BackupArchive string `cfg:"backup_archive"`
+	// FailIfNoMatch returns an error when any rule has no match.
+	FailIfNoMatch bool `cfg:"fail_if_no_match"`
+
+	out io.Writer
 }
 
 type Content struct {
@@ -39,6 +56,9 @@ type Content struct {
 	// Value from load name, key value and type is map[string]any
 	Value      string `cfg:"value"`
 	valueBytes []oldNew
+
+	// FailIfNoMatch returns an error when this rule has no match.
+	FailIfNoMatch bool `cfg:"fail_if_no_match"`
 }
 
 type oldNew struct {
@@ -50,6 +70,18 @@ func (c *Content) Check(v bool) {
 	c.checked = v
 }
 
+// String returns the rule for logs.
+func (c *Content) String() string {
+	switch {
+	case c.Value != "":
+		return "value:" + c.Value
+	case c.Regex != "":
+		return "regex:" + c.Regex
+	default:
+		return "old:" + c.Old
+	}
+}
+
 func (c *Content) set() error {
 	if c.checked {
 		return nil
@@ -128,6 +160,66 @@ func (c *Content) set() error {
 }
 
 func (c *Config) Run(ctx context.Context) error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	for i := range c.Contents {
+		if err := c.Contents[i].set(); err != nil {
+			return err
+		}
+	}
+
+	matches := make([]int, len(c.Contents))
+
+	// count first so a missing match does not leave half replaced files
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		result := b
+		for i := range c.Contents {
+			var n int
+			result, n = replace(result, c.Contents[i])
+			matches[i] += n
+		}
+
+		if c.DryRun && !bytes.Equal(b, result) {
+			if err := c.printDiff(file, b, result); err != nil {
+				return err
+			}
+		}
+	}
+
+	var errs []error
+	for i := range c.Contents {
+		slog.Info("replace summary", "path", c.Path, "rule", c.Contents[i].String(), "matches", matches[i])
+
+		if matches[i] == 0 && (c.FailIfNoMatch || c.Contents[i].FailIfNoMatch) {
+			errs = append(errs, fmt.Errorf("replace rule %s has no match in %s", c.Contents[i].String(), c.Path))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		return nil
+	}
+
+	return c.write(files)
+}
+
+// files returns the files under Path, without the backups of an earlier run.
+func (c *Config) files() ([]string, error) {
 	skipFilesMap := make(map[string]struct{}, len(c.SkipFiles))
 	for _, dir := range c.SkipFiles {
 		skipFilesMap[dir] = struct{}{}
@@ -138,6 +230,16 @@ func (c *Config) Run(ctx context.Context) error {
 		skipDirsMap[dir] = struct{}{}
 	}
 
+	var archive string
+	if c.BackupArchive != "" {
+		var err error
+		if archive, err = filepath.Abs(c.BackupArchive); err != nil {
+			return nil, fmt.Errorf("failed to resolve backup archive path: %w", err)
+		}
+	}
+
+	var files []string
+
 	if err := filepath.Walk(c.Path, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return fmt.Errorf("failure accessing a path %q: %w", path, err)
@@ -157,44 +259,124 @@ func (c *Config) Run(ctx context.Context) error {
 			slog.Debug("skip file", "file", path)
 		}
 
+		if strings.HasSuffix(path, ".bak") {
+			return nil
+		}
+
+		if archive != "" {
+			if abs, err := filepath.Abs(path); err == nil && abs == archive {
+				return nil
+			}
+		}
+
+		files = append(files, path)
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walking the path: %w", err)
+	}
+
+	return files, nil
+}
+
+// write replaces the files, backing up the changed ones first.
+func (c *Config) write(files []string) (err error) {
+	var archive *backupArchive
+	if c.BackupArchive != "" {
+		archive = newBackupArchive(c.BackupArchive)
+
+		defer func() {
+			err = errors.Join(err, archive.Close())
+		}()
+	}
+
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		result := b
 		for i := range c.Contents {
-			if err := c.Contents[i].set(); err != nil {
+			result, _ = replace(result, c.Contents[i])
+		}
+
+		if bytes.Equal(b, result) {
+			continue
+		}
+
+		if c.Backup {
+			if err := backupFile(file, b); err != nil {
 				return err
 			}
+		}
 
-			if err := replace(path, c.Contents[i]); err != nil {
+		if archive != nil {
+			if err := archive.Add(c.Path, file, b); err != nil {
 				return err
 			}
 		}
 
-		return nil
-	}); err != nil {
-		return fmt.Errorf("failed to walking the path: %w", err)
+		// Write to file
+		if err := os.WriteFile(file, result, 0); err != nil {
+			return err
+		}
+
+		slog.Debug("replaced", "file", file)
 	}
 
 	return nil
 }
 
-func replace(file string, content Content) error {
-	// Read file
-	b, err := os.ReadFile(file)
+func (c *Config) printDiff(file string, old, new []byte) error {
+	out := c.out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(new, 0) >= 0 {
+		_, err := fmt.Fprintf(out, "Binary file %s differs\n", file)
+
+		return err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(old)),
+		B:        difflib.SplitLines(string(new)),
+		FromFile: file,
+		ToFile:   file,
+		Context:  3,
+	})
 	if err != nil {
 		return err
 	}
 
+	_, err = io.WriteString(out, diff)
+
+	return err
+}
+
+// replace returns the replaced content and the number of matches.
+func replace(b []byte, content Content) ([]byte, int) {
+	var n int
+
 	if content.valueBytes != nil {
 		for _, value := range content.valueBytes {
+			n += bytes.Count(b, value.Old)
 			b = bytes.ReplaceAll(b, value.Old, value.New)
 		}
-	} else {
-		// Replace old content with new content
-		if content.reg != nil {
-			b = content.reg.ReplaceAll(b, content.new)
-		} else {
-			b = bytes.ReplaceAll(b, content.old, content.new)
-		}
+
+		return b, n
 	}
 
-	// Write to file
-	return os.WriteFile(file, b, 0)
+	// Replace old content with new content
+	if content.reg != nil {
+		n = len(content.reg.FindAllIndex(b, -1))
+
+		return content.reg.ReplaceAll(b, content.new), n
+	}
+
+	n = bytes.Count(b, content.old)
+
+	return bytes.ReplaceAll(b, content.old, content.new), n
 }
@@ -0,0 +1,256 @@
+package replace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBundle(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"index.html":    "<script>window.API = \"__API_URL__\"</script>\n",
+		"assets/app.js": "fetch(\"__API_URL__/users\")\nconst v = \"version: 1\"\n",
+		"robots.txt":    "User-agent: *\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	v, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(v)
+}
+
+func readArchive(t *testing.T, name string) map[string]string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got[hdr.Name] = string(v)
+	}
+
+	return got
+}
+
+func TestConfig_Run(t *testing.T) {
+	dir := writeBundle(t)
+	archive := filepath.Join(t.TempDir(), "restore.tar.gz")
+
+	config := Config{
+		Path: dir,
+		Contents: []Content{
+			{Old: "__API_URL__", New: "https://api.example.com"},
+			{Regex: `version: \d+`, New: "version: 2"},
+		},
+		Backup:        true,
+		BackupArchive: archive,
+		FailIfNoMatch: true,
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "assets/app.js")); got != "fetch(\"https://api.example.com/users\")\nconst v = \"version: 2\"\n" {
+		t.Errorf("Config.Run() app.js = %q", got)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "index.html.bak")); !strings.Contains(got, "__API_URL__") {
+		t.Errorf("Config.Run() index.html.bak = %q, want original", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "robots.txt.bak")); !os.IsNotExist(err) {
+		t.Errorf("Config.Run() backup of unchanged file, err = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("Config.Run() mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	got := readArchive(t, archive)
+
+	if len(got) != 2 || !strings.Contains(got["index.html"], "__API_URL__") || !strings.Contains(got["assets/app.js"], "version: 1") {
+		t.Errorf("Config.Run() archive = %v", got)
+	}
+}
+
+func TestConfig_RunTwice(t *testing.T) {
+	dir := writeBundle(t)
+	archive := filepath.Join(dir, "restore.tar.gz")
+
+	config := Config{
+		Path: dir,
+		Contents: []Content{
+			{Old: "__API_URL__", New: "https://api.example.com"},
+		},
+		Backup:        true,
+		BackupArchive: archive,
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	// the restarted container replaces the files again
+	config.Contents = []Content{{Old: "window.API", New: "window.APP"}}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() second error = %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "index.html")); got != "<script>window.APP = \"https://api.example.com\"</script>\n" {
+		t.Errorf("Config.Run() index.html = %q", got)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "index.html.bak")); got != "<script>window.API = \"__API_URL__\"</script>\n" {
+		t.Errorf("Config.Run() index.html.bak = %q, want first original", got)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.bak.bak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 0 {
+		t.Errorf("Config.Run() backups of backups = %v", matches)
+	}
+
+	got := readArchive(t, archive)
+	if len(got) != 2 || !strings.Contains(got["index.html"], "__API_URL__") || !strings.Contains(got["assets/app.js"], "__API_URL__") {
+		t.Errorf("Config.Run() archive = %v, want first originals", got)
+	}
+}
+
+func TestConfig_RunBackupArchiveNoChange(t *testing.T) {
+	dir := writeBundle(t)
+	archive := filepath.Join(t.TempDir(), "restore.tar.gz")
+
+	config := Config{
+		Path:          dir,
+		Contents:      []Content{{Old: "__MISSING__", New: "x"}},
+		BackupArchive: archive,
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Errorf("Config.Run() archive without changes, err = %v", err)
+	}
+}
+
+func TestConfig_RunDryRun(t *testing.T) {
+	dir := writeBundle(t)
+
+	var out bytes.Buffer
+
+	config := Config{
+		Path:     dir,
+		Contents: []Content{{Old: "__API_URL__", New: "https://api.example.com"}},
+		DryRun:   true,
+		out:      &out,
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "index.html")); !strings.Contains(got, "__API_URL__") {
+		t.Errorf("Config.Run() dry run changed index.html = %q", got)
+	}
+
+	for _, want := range []string{
+		"--- " + filepath.Join(dir, "index.html"),
+		"-<script>window.API = \"__API_URL__\"</script>",
+		"+<script>window.API = \"https://api.example.com\"</script>",
+		"+fetch(\"https://api.example.com/users\")",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Config.Run() diff does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "robots.txt") {
+		t.Errorf("Config.Run() diff has unchanged file:\n%s", out.String())
+	}
+}
+
+func TestConfig_RunFailIfNoMatch(t *testing.T) {
+	dir := writeBundle(t)
+
+	config := Config{
+		Path: dir,
+		Contents: []Content{
+			{Old: "__API_URL__", New: "https://api.example.com"},
+			{Old: "__MISSING__", New: "x", FailIfNoMatch: true},
+		},
+	}
+
+	err := config.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "old:__MISSING__ has no match") {
+		t.Fatalf("Config.Run() error = %v, want no match", err)
+	}
+
+	// nothing is written when a rule fails
+	if got := readFile(t, filepath.Join(dir, "index.html")); !strings.Contains(got, "__API_URL__") {
+		t.Errorf("Config.Run() changed index.html = %q", got)
+	}
+}
@@ -0,0 +1,177 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+type Config struct {
+	// Source is the directory with the templates.
+	Source string `cfg:"source"`
+	// Destination is the directory to write the rendered files.
+	Destination string `cfg:"destination"`
+	// Include renders only matching files, use glob pattern.
+	//  - Patterns match the slash separated path relative to Source, a
+	//    pattern without a slash matches the file name.
+	Include []string `cfg:"include"`
+	// Exclude skips matching files and directories, use glob pattern.
+	Exclude []string `cfg:"exclude"`
+	// LeftDelim and RightDelim change the template delimiters, default is {{ and }}.
+	LeftDelim  string `cfg:"left_delim"`
+	RightDelim string `cfg:"right_delim"`
+	// OnlyChanged skips writing files with the same content and mode.
+	OnlyChanged bool `cfg:"only_changed"`
+}
+
+func (c *Config) Run(ctx context.Context) error {
+	if c.Source == "" || c.Destination == "" {
+		return errors.New("template source and destination are required")
+	}
+
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid template pattern %q: %w", pattern, err)
+		}
+	}
+
+	if err := filepath.WalkDir(c.Source, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failure accessing a path %q: %w", file, err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err //nolint:wrapcheck // context error
+		}
+
+		rel, err := filepath.Rel(c.Source, file)
+		if err != nil {
+			return err //nolint:wrapcheck // walked under source
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(c.Exclude, rel) {
+			slog.Debug("skip template path", "path", file)
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() || len(c.Include) > 0 && !matchAny(c.Include, rel) {
+			return nil
+		}
+
+		return c.render(file, filepath.Join(c.Destination, filepath.FromSlash(rel)))
+	}); err != nil {
+		return fmt.Errorf("failed to render templates: %w", err)
+	}
+
+	return nil
+}
+
+// render writes the rendered src to dst with the mode of src, missing
+// directories are created with the modes of the source directories.
+func (c *Config) render(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err //nolint:wrapcheck // path in error
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err //nolint:wrapcheck // path in error
+	}
+
+	result, err := render.ExecuteWithDelims(string(content), render.Data, c.LeftDelim, c.RightDelim)
+	if err != nil {
+		return fmt.Errorf("failed to render template %s: %w", src, err)
+	}
+
+	mode := info.Mode().Perm()
+
+	if c.OnlyChanged {
+		if dstInfo, err := os.Stat(dst); err == nil && dstInfo.Mode().Perm() == mode {
+			if current, err := os.ReadFile(dst); err == nil && bytes.Equal(current, result) {
+				slog.Debug("template not changed", "file", dst)
+
+				return nil
+			}
+		}
+	}
+
+	if err := c.mkdirAll(filepath.Dir(src), filepath.Dir(dst)); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(dst, result, mode); err != nil {
+		return err //nolint:wrapcheck // path in error
+	}
+
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(dst, mode); err != nil {
+		return err //nolint:wrapcheck // path in error
+	}
+
+	slog.Debug("template rendered", "file", dst)
+
+	return nil
+}
+
+// mkdirAll creates dstDir and its missing parents with the modes of the
+// matching source directories.
+func (c *Config) mkdirAll(srcDir, dstDir string) error {
+	if _, err := os.Stat(dstDir); err == nil {
+		return nil
+	}
+
+	mode := fs.FileMode(0o755)
+	if info, err := os.Stat(srcDir); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if dstDir == filepath.Clean(c.Destination) {
+		return os.MkdirAll(dstDir, mode) //nolint:wrapcheck // path in error
+	}
+
+	if err := c.mkdirAll(filepath.Dir(srcDir), filepath.Dir(dstDir)); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(dstDir, mode); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err //nolint:wrapcheck // path in error
+	}
+
+	return nil
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok && !strings.Contains(pattern, "/") {
+			return true
+		}
+	}
+
+	return false
+}
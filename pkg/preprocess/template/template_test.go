@@ -0,0 +1,153 @@
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+func writeFile(t *testing.T, name, content string, mode os.FileMode) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfig_Run(t *testing.T) {
+	render.Data = map[string]any{"app": map[string]any{"name": "turna"}}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "out")
+
+	writeFile(t, filepath.Join(src, "config.yaml"), "name: {{ .app.name }}\n", 0o644)
+	writeFile(t, filepath.Join(src, "bin", "start.sh"), "echo {{ .app.name }}\n", 0o755)
+	writeFile(t, filepath.Join(src, "web", "index.html"), "<title>[[ .app.name ]]</title>{{ raw }}", 0o600)
+	writeFile(t, filepath.Join(src, "web", "skip.tmp"), "{{ invalid", 0o644)
+	writeFile(t, filepath.Join(src, "node_modules", "x.js"), "{{ invalid", 0o644)
+
+	tests := []struct {
+		name   string
+		config Config
+		want   map[string]string
+		modes  map[string]os.FileMode
+	}{
+		{
+			name: "default delims",
+			config: Config{
+				Source:      src,
+				Destination: dst,
+				Exclude:     []string{"*.tmp", "node_modules", "web/index.html"},
+			},
+			want: map[string]string{
+				"config.yaml":  "name: turna\n",
+				"bin/start.sh": "echo turna\n",
+			},
+			modes: map[string]os.FileMode{
+				"config.yaml":  0o644,
+				"bin/start.sh": 0o755,
+			},
+		},
+		{
+			name: "custom delims with include",
+			config: Config{
+				Source:      src,
+				Destination: dst,
+				Include:     []string{"web/*.html"},
+				LeftDelim:   "[[",
+				RightDelim:  "]]",
+			},
+			want: map[string]string{
+				"web/index.html": "<title>turna</title>{{ raw }}",
+			},
+			modes: map[string]os.FileMode{
+				"web/index.html": 0o600,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Run(context.Background()); err != nil {
+				t.Fatalf("Config.Run() error = %v", err)
+			}
+
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dst, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(got) != want {
+					t.Errorf("Config.Run() %s = %q, want %q", name, got, want)
+				}
+			}
+
+			for name, want := range tt.modes {
+				info, err := os.Stat(filepath.Join(dst, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if info.Mode().Perm() != want {
+					t.Errorf("Config.Run() %s mode = %v, want %v", name, info.Mode().Perm(), want)
+				}
+			}
+		})
+	}
+
+	for _, name := range []string{"web/skip.tmp", "node_modules/x.js"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("Config.Run() %s is written, err = %v", name, err)
+		}
+	}
+}
+
+func TestConfig_RunOnlyChanged(t *testing.T) {
+	render.Data = map[string]any{"version": "1"}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	writeFile(t, filepath.Join(src, "a.txt"), "a {{ .version }}", 0o644)
+	writeFile(t, filepath.Join(src, "b.txt"), "b", 0o644)
+
+	config := Config{Source: src, Destination: dst, OnlyChanged: true}
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.Chtimes(filepath.Join(dst, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	render.Data = map[string]any{"version": "2"}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+
+	for name, changed := range map[string]bool{"a.txt": true, "b.txt": false} {
+		info, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := !info.ModTime().Equal(old); got != changed {
+			t.Errorf("Config.Run() %s rewritten = %v, want %v", name, got, changed)
+		}
+	}
+}
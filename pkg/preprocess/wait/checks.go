@@ -0,0 +1,158 @@
+package wait
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxBody is the size of the response body read for Body and BodyRegex.
+const maxBody = 1 << 20
+
+type HTTP struct {
+	URL    string `cfg:"url"`
+	Method string `cfg:"method"`
+	// Headers are added to the request.
+	Headers map[string]string `cfg:"headers"`
+	// Status is the list of accepted status codes, default is any 2xx.
+	Status []int `cfg:"status"`
+	// Body must be in the response body.
+	Body string `cfg:"body"`
+	// BodyRegex must match the response body.
+	BodyRegex string `cfg:"body_regex"`
+	// InsecureSkipVerify skips the TLS certificate check.
+	InsecureSkipVerify bool `cfg:"insecure_skip_verify"`
+
+	client *http.Client
+	regex  *regexp.Regexp
+}
+
+func (h *HTTP) checker() (checker, error) {
+	if h.URL == "" {
+		return nil, errors.New("http url is required")
+	}
+
+	if h.BodyRegex != "" {
+		regex, err := regexp.Compile(h.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("http body_regex: %w", err)
+		}
+
+		h.regex = regex
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if h.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // user option
+	}
+
+	h.client = &http.Client{Transport: transport}
+
+	return h, nil
+}
+
+func (h *HTTP) check(ctx context.Context) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if len(h.Status) > 0 && !slices.Contains(h.Status, resp.StatusCode) ||
+		len(h.Status) == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if h.Body == "" && h.regex == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return err
+	}
+
+	if h.Body != "" && !strings.Contains(string(body), h.Body) {
+		return fmt.Errorf("body does not contain %q", h.Body)
+	}
+
+	if h.regex != nil && !h.regex.Match(body) {
+		return fmt.Errorf("body does not match %q", h.BodyRegex)
+	}
+
+	return nil
+}
+
+func (h *HTTP) String() string {
+	return "http " + h.URL
+}
+
+type tcpCheck string
+
+func (t tcpCheck) check(ctx context.Context) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", string(t))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (t tcpCheck) String() string {
+	return "tcp " + string(t)
+}
+
+type dnsCheck string
+
+func (d dnsCheck) check(ctx context.Context) error {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, string(d))
+	if err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("no address for %s", string(d))
+	}
+
+	return nil
+}
+
+func (d dnsCheck) String() string {
+	return "dns " + string(d)
+}
+
+type fileCheck string
+
+func (f fileCheck) check(_ context.Context) error {
+	_, err := os.Stat(string(f))
+
+	return err
+}
+
+func (f fileCheck) String() string {
+	return "file " + string(f)
+}
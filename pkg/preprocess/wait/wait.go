@@ -0,0 +1,157 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout        = time.Minute
+	defaultInterval       = time.Second
+	defaultAttemptTimeout = 5 * time.Second
+)
+
+type Config struct {
+	// Timeout is the default time to wait for a check, default is 1m.
+	Timeout time.Duration `cfg:"timeout"`
+	// Interval is the default time between attempts, default is 1s.
+	Interval time.Duration `cfg:"interval"`
+	// Checks run at the same time, Run returns when all of them are ready.
+	Checks []Check `cfg:"checks"`
+}
+
+// Check waits for one of TCP, HTTP, DNS or File.
+type Check struct {
+	// Name is used in logs, default is the checked target.
+	Name string `cfg:"name"`
+	// TCP is an address to connect, like localhost:5432.
+	TCP string `cfg:"tcp"`
+	// HTTP is an endpoint with an expected response.
+	HTTP *HTTP `cfg:"http"`
+	// DNS is a host name to resolve.
+	DNS string `cfg:"dns"`
+	// File is a path that must exist.
+	File string `cfg:"file"`
+
+	// Timeout overrides the config timeout.
+	Timeout time.Duration `cfg:"timeout"`
+	// Interval overrides the config interval.
+	Interval time.Duration `cfg:"interval"`
+	// AttemptTimeout limits one attempt, default is 5s.
+	AttemptTimeout time.Duration `cfg:"attempt_timeout"`
+}
+
+type checker interface {
+	check(ctx context.Context) error
+	String() string
+}
+
+func (c *Config) Run(ctx context.Context) error {
+	checkers := make([]checker, 0, len(c.Checks))
+	for i := range c.Checks {
+		v, err := c.Checks[i].checker()
+		if err != nil {
+			return fmt.Errorf("wait check %d: %w", i, err)
+		}
+
+		checkers = append(checkers, v)
+	}
+
+	errs := make([]error, len(c.Checks))
+
+	var wg sync.WaitGroup
+	for i := range c.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			errs[i] = c.Checks[i].wait(ctx, checkers[i], c.Timeout, c.Interval)
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (c *Check) checker() (checker, error) {
+	var v []checker
+
+	if c.TCP != "" {
+		v = append(v, tcpCheck(c.TCP))
+	}
+
+	if c.HTTP != nil {
+		h, err := c.HTTP.checker()
+		if err != nil {
+			return nil, err
+		}
+
+		v = append(v, h)
+	}
+
+	if c.DNS != "" {
+		v = append(v, dnsCheck(c.DNS))
+	}
+
+	if c.File != "" {
+		v = append(v, fileCheck(c.File))
+	}
+
+	if len(v) != 1 {
+		return nil, errors.New("set one of tcp, http, dns or file")
+	}
+
+	return v[0], nil
+}
+
+// wait retries the check until it succeeds or the timeout passes.
+func (c *Check) wait(ctx context.Context, v checker, timeout, interval time.Duration) error {
+	timeout = firstDuration(c.Timeout, timeout, defaultTimeout)
+	interval = firstDuration(c.Interval, interval, defaultInterval)
+	attemptTimeout := firstDuration(c.AttemptTimeout, defaultAttemptTimeout)
+
+	name := c.Name
+	if name == "" {
+		name = v.String()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	for {
+		attemptCtx, attemptCancel := context.WithTimeout(ctx, attemptTimeout)
+		err := v.check(attemptCtx)
+		attemptCancel()
+
+		if err == nil {
+			slog.Info("wait check ready", "check", name, "duration", time.Since(start).String())
+
+			return nil
+		}
+
+		slog.Debug("wait check not ready", "check", name, "err", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait %s not ready after %s: %w", name, timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func firstDuration(v ...time.Duration) time.Duration {
+	for _, d := range v {
+		if d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
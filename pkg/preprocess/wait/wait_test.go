@@ -0,0 +1,113 @@
+package wait
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfig_Run(t *testing.T) {
+	var ready atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status":"UP"}`))
+	}))
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	file := filepath.Join(t.TempDir(), "ready")
+
+	// the http endpoint and the file become ready after a while
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ready.Store(true)
+		_ = os.WriteFile(file, nil, 0o600)
+	}()
+
+	config := Config{
+		Timeout:  5 * time.Second,
+		Interval: 10 * time.Millisecond,
+		Checks: []Check{
+			{TCP: l.Addr().String()},
+			{HTTP: &HTTP{URL: srv.URL, Body: `"UP"`, BodyRegex: `status"\s*:`}},
+			{DNS: "localhost"},
+			{File: file},
+		},
+	}
+
+	if err := config.Run(context.Background()); err != nil {
+		t.Fatalf("Config.Run() error = %v", err)
+	}
+}
+
+func TestConfig_RunError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		check   Check
+		wantErr string
+	}{
+		{
+			name:    "no check",
+			check:   Check{},
+			wantErr: "set one of",
+		},
+		{
+			name:    "two checks",
+			check:   Check{TCP: "localhost:1", File: "x"},
+			wantErr: "set one of",
+		},
+		{
+			name:    "status",
+			check:   Check{HTTP: &HTTP{URL: srv.URL}},
+			wantErr: "unexpected status 418",
+		},
+		{
+			name:    "expected status without body",
+			check:   Check{HTTP: &HTTP{URL: srv.URL, Status: []int{http.StatusTeapot}, Body: "ok"}},
+			wantErr: `body does not contain "ok"`,
+		},
+		{
+			name:    "file",
+			check:   Check{Name: "init job", File: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "wait init job not ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				Timeout:  50 * time.Millisecond,
+				Interval: 10 * time.Millisecond,
+				Checks:   []Check{tt.check},
+			}
+
+			err := config.Run(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Config.Run() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"bytes"
 	"log/slog"
 	"sync"
 
@@ -47,3 +48,44 @@ func Validate(content string) error {
 
 	return validateTemplate.Parse(content)
 }
+
+// delimsTemplates caches templates with custom delimiters by left and right.
+var (
+	delimsTemplates = make(map[[2]string]*templatex.Template)
+	delimsMu        sync.Mutex
+)
+
+// ExecuteWithDelims renders content like ExecuteWithData with custom template
+// delimiters, an empty delimiter is the default one.
+func ExecuteWithDelims(content string, data any, left, right string) ([]byte, error) {
+	if left == "" && right == "" {
+		return ExecuteWithData(content, data)
+	}
+
+	delimsMu.Lock()
+	defer delimsMu.Unlock()
+
+	key := [2]string{left, right}
+
+	tpl, ok := delimsTemplates[key]
+	if !ok {
+		tpl = templatex.New(
+			templatex.WithAddFuncMapWithOpts(func(o templatex.Option) map[string]any {
+				return fstore.FuncMap(
+					fstore.WithLog(slog.Default()),
+					fstore.WithTrust(true),
+					fstore.WithExecuteTemplate(o.T),
+				)
+			}),
+		).SetDelims(left, right)
+
+		delimsTemplates[key] = tpl
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(templatex.WithIO(&buf), templatex.WithContent(content), templatex.WithData(data)); err != nil {
+		return nil, err //nolint:wrapcheck // same as ExecuteWithData
+	}
+
+	return buf.Bytes(), nil
+}
@@ -56,3 +56,44 @@ func TestRender_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestRender_ExecuteWithDelims(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		left    string
+		right   string
+		want    string
+	}{
+		{
+			name:    "default",
+			content: "{{ .test }}",
+			want:    "value",
+		},
+		{
+			name:    "custom",
+			content: "[[ .test ]] {{ .test }}",
+			left:    "[[",
+			right:   "]]",
+			want:    "value {{ .test }}",
+		},
+		{
+			name:    "custom with funcs",
+			content: "<% upper .test %>",
+			left:    "<%",
+			right:   "%>",
+			want:    "VALUE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExecuteWithDelims(tt.content, map[string]any{"test": "value"}, tt.left, tt.right)
+			if err != nil {
+				t.Fatalf("ExecuteWithDelims() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ExecuteWithDelims() = %v, want %v", string(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// ConditionStarted is met when the process is started.
+	ConditionStarted = "started"
+	// ConditionHealthy is met when the health check passes the first time.
+	ConditionHealthy = "healthy"
+	// ConditionCompleted is met when the process exits without an error.
+	ConditionCompleted = "completed"
+)
+
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	HealthStopped   = "stopped"
+
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthTimeout  = 3 * time.Second
+	defaultHealthRetries  = 3
+)
+
+// HealthCheck checks a running process.
+type HealthCheck struct {
+	// Check returns an error when the process is not healthy.
+	Check func(ctx context.Context) error
+	// Interval between checks, default is 5s.
+	Interval time.Duration
+	// Timeout of a check, default is 3s.
+	Timeout time.Duration
+	// Retries is the number of failures in a row to be unhealthy, default is 3.
+	Retries int
+	// StartPeriod is the time after start where failures are not counted.
+	StartPeriod time.Duration
+}
+
+func validCondition(condition string) bool {
+	switch condition {
+	case ConditionStarted, ConditionHealthy, ConditionCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// condition returns the condition of the dependency.
+func (c *Command) condition(depend string) string {
+	if v := c.DependsCondition[depend]; v != "" {
+		return v
+	}
+
+	return ConditionCompleted
+}
+
+// Health returns the health state of the process, empty without a health
+// check.
+func (c *Command) Health() string {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.health
+}
+
+func (c *Command) setHealth(health string, err error) {
+	c.stateLock.Lock()
+	previous := c.health
+	c.health = health
+	c.stateLock.Unlock()
+
+	if previous == health {
+		return
+	}
+
+	switch health {
+	case HealthHealthy:
+		slog.Info(fmt.Sprintf("process [%s] is healthy", c.Name))
+	case HealthUnhealthy:
+		slog.Warn(fmt.Sprintf("process [%s] is unhealthy", c.Name), "err", err)
+	}
+}
+
+// checkHealth runs the health check until the process exits.
+func (c *Command) checkHealth(ctx context.Context, done <-chan struct{}) {
+	h := c.HealthCheck
+
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	retries := h.Retries
+	if retries <= 0 {
+		retries = defaultHealthRetries
+	}
+
+	c.setHealth(HealthStarting, nil)
+
+	started := time.Now()
+	failures := 0
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := h.Check(checkCtx)
+		cancel()
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if err == nil {
+			failures = 0
+
+			c.setHealth(HealthHealthy, nil)
+			c.emit(ConditionHealthy)
+		} else {
+			slog.Debug(fmt.Sprintf("process [%s] health check failed", c.Name), "err", err)
+
+			if time.Since(started) >= h.StartPeriod {
+				failures++
+			}
+
+			if failures >= retries {
+				c.setHealth(HealthUnhealthy, err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// emit calls onEvent once for each condition.
+func (c *Command) emit(condition string) {
+	c.stateLock.Lock()
+	if _, ok := c.events[condition]; ok {
+		c.stateLock.Unlock()
+
+		return
+	}
+
+	if c.events == nil {
+		c.events = make(map[string]struct{})
+	}
+
+	c.events[condition] = struct{}{}
+	onEvent := c.onEvent
+	c.stateLock.Unlock()
+
+	if onEvent != nil {
+		onEvent(condition)
+	}
+}
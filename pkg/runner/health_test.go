@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoreReg_RunHealthy(t *testing.T) {
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	healthy := filepath.Join(dir, "healthy")
+	started := filepath.Join(dir, "started")
+
+	var checks atomic.Int32
+
+	daemon := &Command{
+		Name: "daemon",
+		// exits after the dependents write their files
+		Command: []string{"sh", "-c", `touch ` + ready + `; while [ ! -f ` + healthy + ` ] || [ ! -f ` + started + ` ]; do sleep 0.05; done`},
+		HealthCheck: &HealthCheck{
+			Check: func(_ context.Context) error {
+				if checks.Add(1) < 3 {
+					return errors.New("not ready")
+				}
+
+				_, err := os.Stat(ready)
+
+				return err
+			},
+			Interval: 20 * time.Millisecond,
+		},
+	}
+
+	wg := new(sync.WaitGroup)
+	s := NewStoreReg(wg)
+
+	for _, c := range []*Command{
+		daemon,
+		{
+			Name:             "app",
+			Command:          []string{"touch", healthy},
+			Depends:          []string{"daemon"},
+			DependsCondition: map[string]string{"daemon": ConditionHealthy},
+		},
+		{
+			Name:             "sidecar",
+			Command:          []string{"touch", started},
+			Depends:          []string{"daemon"},
+			DependsCondition: map[string]string{"daemon": ConditionStarted},
+		},
+	} {
+		if err := s.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(context.Background())
+	}()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("StoreReg.Run() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		daemon.Kill()
+		t.Fatal("StoreReg.Run() dependents not started while daemon runs")
+	}
+
+	wg.Wait()
+
+	if got := daemon.Health(); got != HealthStopped {
+		t.Errorf("Command.Health() = %q, want %q", got, HealthStopped)
+	}
+
+	if got := s.Health(); got["daemon"] != HealthStopped || len(got) != 1 {
+		t.Errorf("StoreReg.Health() = %v", got)
+	}
+}
+
+func TestCommand_checkHealth(t *testing.T) {
+	c := &Command{
+		Name: "unhealthy",
+		HealthCheck: &HealthCheck{
+			Check:    func(_ context.Context) error { return errors.New("down") },
+			Interval: 10 * time.Millisecond,
+			Retries:  2,
+		},
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		c.checkHealth(context.Background(), done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Health() != HealthUnhealthy {
+		if time.Now().After(deadline) {
+			t.Fatalf("Command.Health() = %q, want %q", c.Health(), HealthUnhealthy)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(done)
+	<-finished
+}
+
+func TestStoreReg_dependecySetCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+	}{
+		{name: "healthy without health check", condition: ConditionHealthy},
+		{name: "unknown condition", condition: "ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStoreReg(new(sync.WaitGroup))
+
+			for _, c := range []*Command{
+				{Name: "db", Command: []string{"true"}},
+				{
+					Name:             "app",
+					Command:          []string{"true"},
+					Depends:          []string{"db"},
+					DependsCondition: map[string]string{"db": tt.condition},
+				},
+			} {
+				if err := s.Add(c); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := s.Run(context.Background()); err == nil {
+				t.Error("StoreReg.Run() want error")
+			}
+		})
+	}
+}
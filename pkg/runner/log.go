@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"io"
+
+	"github.com/rakunlabs/turna/pkg/filter"
+)
+
+// Log routes the output of the process.
+type Log struct {
+	// Prefix adds [name] to the lines.
+	Prefix bool
+	// JSON writes the lines as JSON log records with a service attribute,
+	// Prefix is not used.
+	JSON bool
+	// File also receives the lines, optional.
+	File io.Writer
+}
+
+func (l *Log) enabled() bool {
+	return l.Prefix || l.JSON || l.File != nil
+}
+
+// fileFilter returns the filter of a stream writing to w.
+func (c *Command) fileFilter(w io.Writer, stream string) *filter.FileFilter {
+	f := &filter.FileFilter{To: w, Filter: c.Filter}
+
+	var format func([]byte) []byte
+
+	switch {
+	case c.Log.JSON:
+		format = filter.JSONFormat(c.Name, stream)
+	case c.Log.Prefix:
+		format = filter.PrefixFormat("[" + c.Name + "] ")
+	}
+
+	switch {
+	case c.Rewrite != nil && format != nil:
+		f.Format = func(line []byte) []byte {
+			return format(c.Rewrite(line))
+		}
+	case c.Rewrite != nil:
+		f.Format = c.Rewrite
+	default:
+		f.Format = format
+	}
+
+	if c.Log.File != nil {
+		f.To = io.MultiWriter(w, c.Log.File)
+	}
+
+	return f
+}
@@ -20,6 +20,10 @@ type StoreReg struct {
 	order []*OrderCommand
 	wg    *sync.WaitGroup
 	rwm   sync.RWMutex
+
+	// stopped is set by KillAll, no command is started after it.
+	stopped  bool
+	killLock sync.Mutex
 }
 
 type OrderCommand struct {
@@ -29,51 +33,31 @@ type OrderCommand struct {
 	StoreReg *StoreReg
 }
 
-func (o *OrderCommand) Run(ctxParent context.Context, wg *sync.WaitGroup) error {
+func (o *OrderCommand) Run(ctxParent context.Context) error {
 	o.wg = &sync.WaitGroup{}
 	ctx, ctxCancel := context.WithCancel(ctxParent)
 	defer ctxCancel()
 
-	var errStore []error
+	var (
+		errStore []error
+		errMu    sync.Mutex
+	)
 
 	for _, name := range o.Names {
 		o.wg.Add(1)
 		go func(name string) {
 			defer o.wg.Done()
 
-			// run command
+			// run command, dependencies are triggered after it completes
 			if err := o.StoreReg.reg[name].Run(ctx); err != nil {
 				slog.Error(fmt.Sprintf("failed command [%s]", name), "err", err.Error())
 
+				errMu.Lock()
 				errStore = append(errStore, err)
+				errMu.Unlock()
 
 				ctxCancel()
-
-				return
 			}
-
-			// run dependencies
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-
-				ctx, ctxCancel := context.WithCancel(ctxParent)
-				defer ctxCancel()
-
-				for _, depend := range o.StoreReg.reg[name].trigger {
-					slog.Info(fmt.Sprintf("command [%s] dependecy trigger [%s]", name, depend))
-
-					if err := o.StoreReg.reg[depend].DependecyTrigger(ctx, name); err != nil {
-						slog.Error(fmt.Sprintf("failed command [%s]", name), "err", err.Error())
-
-						errStore = append(errStore, err)
-
-						ctxCancel()
-
-						return
-					}
-				}
-			}()
 		}(name)
 	}
 
@@ -99,6 +83,15 @@ func (s *StoreReg) dependecySet() error {
 				return fmt.Errorf("dependecy [%s] not found for [%s]", depend, name)
 			}
 
+			condition := s.reg[name].condition(depend)
+			if !validCondition(condition) {
+				return fmt.Errorf("unknown condition [%s] of dependecy [%s] for [%s]", condition, depend, name)
+			}
+
+			if condition == ConditionHealthy && s.reg[depend].HealthCheck == nil {
+				return fmt.Errorf("dependecy [%s] of [%s] has no health check", depend, name)
+			}
+
 			s.reg[depend].trigger = append(s.reg[depend].trigger, name)
 		}
 	}
@@ -138,6 +131,31 @@ func (s *StoreReg) dependecySet() error {
 	return nil
 }
 
+// triggerDepends runs the commands waiting the condition of name, each
+// condition triggers them only once.
+func (s *StoreReg) triggerDepends(ctx context.Context, name, condition string) {
+	if s.isStopped() {
+		return
+	}
+
+	for _, depend := range s.reg[name].trigger {
+		if s.reg[depend].condition(name) != condition {
+			continue
+		}
+
+		slog.Info(fmt.Sprintf("command [%s] %s dependecy trigger [%s]", name, condition, depend))
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			if err := s.reg[depend].DependecyTrigger(ctx, name); err != nil {
+				slog.Error(fmt.Sprintf("failed command [%s]", depend), "err", err.Error())
+			}
+		}()
+	}
+}
+
 func (s *StoreReg) Add(command *Command) error {
 	s.rwm.Lock()
 	defer s.rwm.Unlock()
@@ -168,16 +186,128 @@ func (s *StoreReg) Get(name string) *Command {
 	return s.reg[name]
 }
 
+// Health returns the health state of the commands with a health check.
+func (s *StoreReg) Health() map[string]string {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+
+	v := make(map[string]string)
+	for name, command := range s.reg {
+		if command.HealthCheck != nil {
+			v[name] = command.Health()
+		}
+	}
+
+	return v
+}
+
+// KillAll stops all commands, a command is stopped after the commands
+// depending on it. Commands in the same level are stopped in parallel.
+// Only the first call stops them, later calls wait for it.
 func (s *StoreReg) KillAll() {
+	s.killLock.Lock()
+	defer s.killLock.Unlock()
+
+	s.rwm.Lock()
+	stopped := s.stopped
+	s.stopped = true
+	s.rwm.Unlock()
+
+	if stopped {
+		return
+	}
+
+	// a dependent triggered before stopped was set cannot start after the
+	// kill of its level
+	s.rwm.RLock()
+	for _, command := range s.reg {
+		command.disable()
+	}
+	s.rwm.RUnlock()
+
 	slog.Warn("killing all process")
 
-	for key := range s.reg {
-		s.reg[key].Kill()
+	for _, names := range s.stopOrder() {
+		wg := sync.WaitGroup{}
+		for _, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				s.Get(name).Kill()
+			}()
+		}
+
+		wg.Wait()
 	}
 
 	slog.Warn("killing all process done")
 }
 
+// stopOrder groups the command names by the longest chain of commands
+// depending on them, the first group has no dependents.
+func (s *StoreReg) stopOrder() [][]string {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+
+	dependents := make(map[string][]string)
+	for name, command := range s.reg {
+		for _, depend := range command.Depends {
+			dependents[depend] = append(dependents[depend], name)
+		}
+	}
+
+	levels := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var level func(name string) int
+	level = func(name string) int {
+		if v, ok := levels[name]; ok {
+			return v
+		}
+
+		// dependency cycle
+		if visiting[name] {
+			return 0
+		}
+
+		visiting[name] = true
+
+		v := 0
+		for _, dependent := range dependents[name] {
+			v = max(v, level(dependent)+1)
+		}
+
+		delete(visiting, name)
+		levels[name] = v
+
+		return v
+	}
+
+	var groups [][]string
+	for name := range s.reg {
+		v := level(name)
+		for len(groups) <= v {
+			groups = append(groups, nil)
+		}
+
+		groups[v] = append(groups[v], name)
+	}
+
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+
+	return groups
+}
+
+func (s *StoreReg) isStopped() bool {
+	s.rwm.RLock()
+	defer s.rwm.RUnlock()
+
+	return s.stopped
+}
+
 func (s *StoreReg) SetAsGlobal() *StoreReg {
 	GlobalReg = s
 
@@ -189,8 +319,29 @@ func (s *StoreReg) Run(ctx context.Context) error {
 		return err
 	}
 
+	// commands are not stopped by the cancel of ctx, KillAll stops them
+	// in the dependency order. After Run the owner of the store calls KillAll.
+	stop := context.AfterFunc(ctx, s.KillAll)
+	defer stop()
+
+	ctx = context.WithoutCancel(ctx)
+
+	for name, command := range s.reg {
+		if len(command.trigger) == 0 {
+			continue
+		}
+
+		command.onEvent = func(condition string) {
+			s.triggerDepends(ctx, name, condition)
+		}
+	}
+
 	for i := range s.order {
-		if err := s.order[i].Run(ctx, s.wg); err != nil {
+		if s.isStopped() {
+			return nil
+		}
+
+		if err := s.order[i].Run(ctx); err != nil {
 			return err
 		}
 	}
@@ -2,8 +2,12 @@ package runner
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestStoreReg_Run(t *testing.T) {
@@ -155,3 +159,153 @@ func TestStoreReg_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreReg_stopOrder(t *testing.T) {
+	s := NewStoreReg(new(sync.WaitGroup))
+
+	for _, c := range []*Command{
+		{Name: "db"},
+		{Name: "cache"},
+		{Name: "api", Depends: []string{"db", "cache"}},
+		{Name: "web", Depends: []string{"api"}},
+		{Name: "worker", Depends: []string{"db"}},
+		{Name: "cron"},
+	} {
+		if err := s.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := [][]string{{"cron", "web", "worker"}, {"api"}, {"cache", "db"}}
+	if got := s.stopOrder(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("StoreReg.stopOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestStoreReg_KillAllOrder(t *testing.T) {
+	dir := t.TempDir()
+	stopped := filepath.Join(dir, "stopped")
+
+	// api stops slower, in parallel db would be stopped first
+	command := func(name, delay string) []string {
+		started := filepath.Join(dir, name)
+
+		return []string{"/bin/sh", "-c", `trap "sleep ` + delay + `; echo ` + name + ` >> ` + stopped + `; exit 0" TERM; touch ` + started + `; while true; do sleep 0.1; done`}
+	}
+
+	wg := new(sync.WaitGroup)
+	s := NewStoreReg(wg)
+
+	for _, c := range []*Command{
+		{Name: "db", Command: command("db", "0")},
+		{Name: "api", Command: command("api", "0.3"), Depends: []string{"db"}, DependsCondition: map[string]string{"db": ConditionStarted}},
+	} {
+		if err := s.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	for _, name := range []string{"db", "api"} {
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("command [%s] not started", name)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// cancel of ctx stops the commands with KillAll
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("StoreReg.Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StoreReg.Run() not stopped")
+	}
+
+	wg.Wait()
+
+	got, err := os.ReadFile(stopped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "api\ndb\n"; string(got) != want {
+		t.Errorf("StoreReg.KillAll() order = %q, want %q", got, want)
+	}
+
+	// the shutdown hook calls KillAll again, the commands are stopped once
+	s.KillAll()
+
+	if got, _ := os.ReadFile(stopped); string(got) != "api\ndb\n" {
+		t.Errorf("StoreReg.KillAll() second call order = %q", got)
+	}
+}
+
+func TestStoreReg_RunStopsCancelCallback(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	s := NewStoreReg(wg)
+
+	if err := s.Add(&Command{Name: "job", Command: []string{"/bin/sh", "-c", "exit 0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := s.Run(ctx); err != nil {
+		t.Fatalf("StoreReg.Run() error = %v", err)
+	}
+
+	wg.Wait()
+
+	// the cancel after Run is left to the owner of the store
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	if s.isStopped() {
+		t.Error("StoreReg.Run() kept the cancel callback after return")
+	}
+}
+
+func TestStoreReg_KillAllBeforeTrigger(t *testing.T) {
+	started := filepath.Join(t.TempDir(), "started")
+
+	wg := new(sync.WaitGroup)
+	s := NewStoreReg(wg)
+
+	for _, c := range []*Command{
+		{Name: "db", Command: []string{"/bin/sh", "-c", "exit 0"}},
+		{Name: "api", Command: []string{"/bin/sh", "-c", "touch " + started}, Depends: []string{"db"}},
+	} {
+		if err := s.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.KillAll()
+
+	// a trigger which passed the stopped check before KillAll
+	if err := s.Get("api").DependecyTrigger(context.Background(), "db"); err != nil {
+		t.Fatalf("Command.DependecyTrigger() error = %v", err)
+	}
+
+	if _, err := os.Stat(started); !os.IsNotExist(err) {
+		t.Errorf("Command.DependecyTrigger() started after KillAll, err = %v", err)
+	}
+}
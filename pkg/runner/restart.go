@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+
+	defaultRestartDelay    = time.Second
+	defaultRestartMaxDelay = time.Minute
+)
+
+// RestartPolicy decides to start a process again after it exits.
+type RestartPolicy struct {
+	// Policy is always, on-failure or never, default is never.
+	Policy string
+	// Delay is the wait before the first restart, doubled on each restart in
+	// Window. Default is 1s.
+	Delay time.Duration
+	// MaxDelay is the maximum wait, default is 1m.
+	MaxDelay time.Duration
+	// MaxRetries is the number of restarts allowed in Window, 0 is unlimited.
+	MaxRetries int
+	// Window is the duration restarts are counted in, 0 counts all restarts.
+	Window time.Duration
+	// Jitter adds a random part of the delay, between 0 and 1.
+	Jitter float64
+}
+
+func (p *RestartPolicy) validate() error {
+	switch strings.ToLower(p.Policy) {
+	case "", RestartNever, RestartAlways, RestartOnFailure:
+	default:
+		return fmt.Errorf("unknown restart policy %q", p.Policy)
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("restart jitter %v must be between 0 and 1", p.Jitter)
+	}
+
+	return nil
+}
+
+// restart reports whether an exit with the code should be restarted.
+func (p *RestartPolicy) restart(code int) bool {
+	switch strings.ToLower(p.Policy) {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return code != 0
+	default:
+		return false
+	}
+}
+
+// backoff counts restarts of a process.
+type backoff struct {
+	policy   *RestartPolicy
+	restarts []time.Time
+}
+
+// next returns the wait before the restart at now, false when MaxRetries is
+// reached in Window.
+func (b *backoff) next(now time.Time) (time.Duration, bool) {
+	if b.policy.Window > 0 {
+		i := 0
+		for i < len(b.restarts) && now.Sub(b.restarts[i]) > b.policy.Window {
+			i++
+		}
+
+		b.restarts = b.restarts[i:]
+	}
+
+	if b.policy.MaxRetries > 0 && len(b.restarts) >= b.policy.MaxRetries {
+		return 0, false
+	}
+
+	delay := b.policy.Delay
+	if delay <= 0 {
+		delay = defaultRestartDelay
+	}
+
+	maxDelay := b.policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRestartMaxDelay
+	}
+
+	for range len(b.restarts) {
+		if delay >= maxDelay {
+			break
+		}
+
+		delay *= 2
+	}
+
+	delay = min(delay, maxDelay)
+
+	if b.policy.Jitter > 0 {
+		delay += time.Duration(rand.Float64() * b.policy.Jitter * float64(delay))
+	}
+
+	b.restarts = append(b.restarts, now)
+
+	return delay, true
+}
@@ -0,0 +1,207 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoff_next(t *testing.T) {
+	now := time.Now()
+
+	b := backoff{policy: &RestartPolicy{
+		Delay:      time.Second,
+		MaxDelay:   5 * time.Second,
+		MaxRetries: 4,
+		Window:     time.Minute,
+	}}
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		got, ok := b.next(now.Add(time.Duration(i) * time.Second))
+		if !ok || got != want {
+			t.Fatalf("backoff.next() %d = %v, %v, want %v", i, got, ok, want)
+		}
+	}
+
+	if _, ok := b.next(now.Add(10 * time.Second)); ok {
+		t.Fatal("backoff.next() want max retries")
+	}
+
+	// restarts out of the window are not counted
+	if got, ok := b.next(now.Add(2 * time.Minute)); !ok || got != time.Second {
+		t.Errorf("backoff.next() after window = %v, %v, want 1s", got, ok)
+	}
+
+	j := backoff{policy: &RestartPolicy{Delay: time.Second, Jitter: 0.5}}
+	for range 10 {
+		j.restarts = nil
+
+		if got, _ := j.next(now); got < time.Second || got > 1500*time.Millisecond {
+			t.Fatalf("backoff.next() jitter = %v", got)
+		}
+	}
+}
+
+func TestRestartPolicy_restart(t *testing.T) {
+	tests := []struct {
+		policy string
+		code   int
+		want   bool
+	}{
+		{policy: "", code: 1, want: false},
+		{policy: RestartNever, code: 1, want: false},
+		{policy: RestartAlways, code: 0, want: true},
+		{policy: RestartOnFailure, code: 0, want: false},
+		{policy: RestartOnFailure, code: 2, want: true},
+	}
+
+	for _, tt := range tests {
+		p := RestartPolicy{Policy: tt.policy}
+		if got := p.restart(tt.code); got != tt.want {
+			t.Errorf("RestartPolicy.restart() %s %d = %v, want %v", tt.policy, tt.code, got, tt.want)
+		}
+	}
+
+	if err := (&RestartPolicy{Policy: "sometimes"}).validate(); err == nil {
+		t.Error("RestartPolicy.validate() want error")
+	}
+}
+
+func TestCommand_RunRestart(t *testing.T) {
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+
+	c := &Command{
+		Name: "fail",
+		// fails two times and then succeeds
+		Command: []string{"sh", "-c", `echo x >> ` + count + `; [ $(wc -l < ` + count + `) -ge 3 ]`},
+		RestartPolicy: RestartPolicy{
+			Policy: RestartOnFailure,
+			Delay:  10 * time.Millisecond,
+		},
+	}
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Command.Run() error = %v", err)
+	}
+
+	if got := runs(t, count); got != 3 {
+		t.Errorf("Command.Run() runs = %d, want 3", got)
+	}
+
+	// max retries returns the last failure
+	limited := filepath.Join(dir, "limited")
+
+	c = &Command{
+		Name:    "limited",
+		Command: []string{"sh", "-c", `echo x >> ` + limited + `; exit 3`},
+		RestartPolicy: RestartPolicy{
+			Policy:     RestartAlways,
+			Delay:      10 * time.Millisecond,
+			MaxRetries: 2,
+		},
+	}
+
+	if err := c.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "code 3") {
+		t.Fatalf("Command.Run() error = %v, want exit code 3", err)
+	}
+
+	if got := runs(t, limited); got != 3 {
+		t.Errorf("Command.Run() runs = %d, want 3", got)
+	}
+}
+
+func TestCommand_KillStopsRestart(t *testing.T) {
+	c := &Command{
+		Name:         "loop",
+		Command:      []string{"sh", "-c", "exit 0"},
+		AllowFailure: true,
+		RestartPolicy: RestartPolicy{
+			Policy: RestartAlways,
+			Delay:  time.Hour,
+		},
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- c.Run(context.Background())
+	}()
+
+	// wait for the backoff of the first exit
+	time.Sleep(200 * time.Millisecond)
+
+	c.Kill()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Command.Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Command.Run() not stopped by Kill")
+	}
+}
+
+func TestStoreReg_RunRestartTriggerOnce(t *testing.T) {
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	dependent := filepath.Join(dir, "dependent")
+
+	wg := new(sync.WaitGroup)
+	s := NewStoreReg(wg)
+
+	for _, c := range []*Command{
+		{
+			Name:    "daemon",
+			Command: []string{"sh", "-c", `echo x >> ` + count},
+			RestartPolicy: RestartPolicy{
+				Policy:     RestartAlways,
+				Delay:      10 * time.Millisecond,
+				MaxRetries: 2,
+			},
+		},
+		{
+			Name:    "dependent",
+			Command: []string{"sh", "-c", `echo x >> ` + dependent},
+			Depends: []string{"daemon"},
+		},
+		{
+			Name:    "chained",
+			Command: []string{"sh", "-c", `echo y >> ` + dependent},
+			Depends: []string{"dependent"},
+		},
+	} {
+		if err := s.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("StoreReg.Run() error = %v", err)
+	}
+
+	wg.Wait()
+
+	if got := runs(t, count); got != 3 {
+		t.Errorf("StoreReg.Run() daemon runs = %d, want 3", got)
+	}
+
+	if got := runs(t, dependent); got != 2 {
+		t.Errorf("StoreReg.Run() dependent runs = %d, want 2", got)
+	}
+}
+
+func runs(t *testing.T, name string) int {
+	t.Helper()
+
+	v, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Count(string(v), "\n")
+}
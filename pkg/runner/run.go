@@ -2,6 +2,7 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,11 +10,18 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-
-	"github.com/rakunlabs/turna/pkg/filter"
+	"syscall"
+	"time"
 )
 
-var ErrRunInit = fmt.Errorf("run init error")
+const defaultStopTimeout = 10 * time.Second
+
+var (
+	ErrRunInit    = fmt.Errorf("run init error")
+	ErrNotRunning = fmt.Errorf("process not running")
+
+	errDisabled = fmt.Errorf("process disabled")
+)
 
 type Command struct {
 	proc         *os.Process
@@ -30,7 +38,39 @@ type Command struct {
 	trigger      []string
 	killLock     sync.Mutex
 	killStarted  bool
+	restart      bool
 	User         string
+	// DependsCondition is the condition of a dependency by name, started,
+	// healthy or completed. Default is completed.
+	DependsCondition map[string]string
+	// RestartPolicy starts the process again after it exits.
+	RestartPolicy RestartPolicy
+	// HealthCheck checks the running process, optional.
+	HealthCheck *HealthCheck
+	// Log routes the output of the process.
+	Log Log
+	// Rewrite changes an output line after Filter, like masking secrets.
+	Rewrite func([]byte) []byte
+	// StopSignal is sent to the process group to stop it, default is SIGTERM.
+	StopSignal os.Signal
+	// StopTimeout is the wait for the process to exit before SIGKILL,
+	// default is 10s.
+	StopTimeout time.Duration
+
+	// procDone is closed when the running process exits.
+	procDone <-chan struct{}
+
+	// stop is closed by Kill to end the restarts.
+	stop chan struct{}
+	// disabled is set by KillAll, no process is started after it.
+	disabled bool
+
+	// onEvent is called once for each met condition, it runs the commands
+	// depending on this one.
+	onEvent   func(condition string)
+	stateLock sync.Mutex
+	events    map[string]struct{}
+	health    string
 
 	dependLock sync.Mutex
 	dependGet  map[string]struct{}
@@ -62,7 +102,7 @@ func (c *Command) DependecyTrigger(ctx context.Context, name string) error {
 	return c.Run(ctx)
 }
 
-func (c *Command) start(ctx context.Context) (*os.Process, error) {
+func (c *Command) start(ctx context.Context, done <-chan struct{}) (*os.Process, error) {
 	var err error
 
 	// command with new path
@@ -104,10 +144,10 @@ func (c *Command) start(ctx context.Context) (*os.Process, error) {
 		stderr = os.Stderr
 	}
 
-	if c.Filter != nil {
+	if c.Filter != nil || c.Rewrite != nil || c.Log.enabled() {
 		slog.Info(fmt.Sprintf("filtering [%s]", c.Name))
 		// filter for stdout
-		filteredStdout := filter.FileFilter{To: stdout, Filter: c.Filter}
+		filteredStdout := c.fileFilter(stdout, "stdout")
 
 		stdout, err = filteredStdout.Start(ctx, &c.wgProg)
 		if err != nil {
@@ -115,7 +155,7 @@ func (c *Command) start(ctx context.Context) (*os.Process, error) {
 		}
 
 		// filter for stderr
-		filteredStderr := filter.FileFilter{To: stderr, Filter: c.Filter}
+		filteredStderr := c.fileFilter(stderr, "stderr")
 
 		stderr, err = filteredStderr.Start(ctx, &c.wgProg)
 		if err != nil {
@@ -151,8 +191,18 @@ func (c *Command) start(ctx context.Context) (*os.Process, error) {
 	go func() {
 		defer c.wgProg.Done()
 
-		<-ctx.Done()
-		c.Kill()
+		select {
+		case <-ctx.Done():
+			// the process exited, a restarted one must not be killed
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			c.kill(false)
+		case <-done:
+		}
 	}()
 
 	return p, nil
@@ -167,6 +217,10 @@ func (c *Command) Run(ctx context.Context) error {
 		return fmt.Errorf("doesn't given any command: %w", ErrRunInit)
 	}
 
+	if err := c.RestartPolicy.validate(); err != nil {
+		return fmt.Errorf("%w: %w", err, ErrRunInit)
+	}
+
 	// for waiting all goroutines
 	defer c.wgProg.Wait()
 
@@ -177,28 +231,128 @@ func (c *Command) Run(ctx context.Context) error {
 	ctx, ctxCancel := context.WithCancel(ctx)
 	defer ctxCancel()
 
+	stop := make(chan struct{})
+	c.killLock.Lock()
+	c.stop = stop
+	c.killLock.Unlock()
+
+	b := backoff{policy: &c.RestartPolicy}
+
+	for {
+		restart, exitCode, err := c.runProcess(ctx)
+		if errors.Is(err, errDisabled) {
+			slog.Info(fmt.Sprintf("process [%s] not started, stopping all", c.Name))
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if restart {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			slog.Info(fmt.Sprintf("process [%s] restarted", c.Name))
+
+			continue
+		}
+
+		err = c.exited(exitCode)
+		if err == nil {
+			c.emit(ConditionCompleted)
+		}
+
+		if !c.RestartPolicy.restart(exitCode) || ctx.Err() != nil || isClosed(stop) {
+			return err
+		}
+
+		delay, ok := b.next(time.Now())
+		if !ok {
+			slog.Error(fmt.Sprintf("process [%s] reached max restarts %d", c.Name, c.RestartPolicy.MaxRetries))
+
+			return err
+		}
+
+		slog.Warn(fmt.Sprintf("process [%s] restarting in %s", c.Name, delay), "exit_code", exitCode)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return err
+		case <-stop:
+			timer.Stop()
+
+			return err
+		}
+	}
+}
+
+// runProcess starts the process and waits for it to exit. It reports whether
+// the exit was caused by RestartProcess and the exit code.
+func (c *Command) runProcess(ctx context.Context) (bool, int, error) {
 	var err error
 
-	slog.Info(fmt.Sprintf("starting [%s] command", c.Name))
+	// stops the goroutines of this process only
+	ctx, ctxCancel := context.WithCancel(ctx)
+	defer ctxCancel()
+
+	done := make(chan struct{})
+
 	c.killLock.Lock()
-	c.proc, err = c.start(ctx)
+	// checked under the lock of disable, KillAll sees every started process
+	if c.disabled {
+		c.killLock.Unlock()
+
+		return false, 0, errDisabled
+	}
+
+	slog.Info(fmt.Sprintf("starting [%s] command", c.Name))
+	c.proc, err = c.start(ctx, done)
+	c.procDone = done
 	c.killLock.Unlock()
 	if err != nil {
-		return err
+		return false, 0, err
 	}
 
-	defer func() {
-		c.killLock.Lock()
-		c.proc = nil
-		c.killLock.Unlock()
-	}()
+	c.emit(ConditionStarted)
+
+	if c.HealthCheck != nil {
+		c.wgProg.Add(1)
+		go func() {
+			defer c.wgProg.Done()
+
+			c.checkHealth(ctx, done)
+		}()
+	}
 
 	state, err := c.proc.Wait()
 	if err != nil {
 		slog.Warn(fmt.Sprintf("process [%s] wait", c.Name), "err", err)
 	}
 
-	exitCode := state.ExitCode()
+	close(done)
+
+	if c.HealthCheck != nil {
+		c.setHealth(HealthStopped, nil)
+	}
+
+	c.killLock.Lock()
+	c.proc = nil
+	restart := c.restart
+	c.restart = false
+	c.killLock.Unlock()
+
+	return restart, state.ExitCode(), nil
+}
+
+// exited logs the exit code and returns an error for a failure not allowed.
+func (c *Command) exited(exitCode int) error {
 	if exitCode != 0 {
 		slog.Warn(fmt.Sprintf("process [%s] exited with code %d", c.Name, exitCode))
 		if !c.AllowFailure {
@@ -211,11 +365,103 @@ func (c *Command) Run(ctx context.Context) error {
 	return nil
 }
 
-// Kill the kill command.
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Signal sends sig to the running process.
+func (c *Command) Signal(sig os.Signal) error {
+	c.killLock.Lock()
+	defer c.killLock.Unlock()
+
+	if c.proc == nil {
+		return fmt.Errorf("process [%s]: %w", c.Name, ErrNotRunning)
+	}
+
+	slog.Info(fmt.Sprintf("sending signal %s to process [%s] [%d]", sig, c.Name, c.proc.Pid))
+
+	return c.proc.Signal(sig)
+}
+
+// RestartProcess terminates the running process and lets Run start it again,
+// without reporting the termination as a failure.
+func (c *Command) RestartProcess() error {
+	c.killLock.Lock()
+	defer c.killLock.Unlock()
+
+	if c.proc == nil {
+		return fmt.Errorf("process [%s]: %w", c.Name, ErrNotRunning)
+	}
+
+	slog.Info(fmt.Sprintf("restarting process [%s] [%d]", c.Name, c.proc.Pid))
+
+	c.restart = true
+
+	return c.terminate(c.proc, c.procDone)
+}
+
+// terminate sends the stop signal to the process group and kills the group
+// when the process does not exit in the stop timeout.
+func (c *Command) terminate(p *os.Process, done <-chan struct{}) error {
+	sig := c.StopSignal
+	if sig == nil {
+		sig = syscall.SIGTERM
+	}
+
+	if err := terminateProcess(p.Pid, sig); err != nil {
+		return err
+	}
+
+	timeout := c.StopTimeout
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			slog.Warn(fmt.Sprintf("process [%s] [%d] not stopped in %s, sending SIGKILL", c.Name, p.Pid, timeout))
+
+			if err := killProcess(p.Pid); err != nil {
+				slog.Error(fmt.Sprintf("failed to kill process [%s] [%d]", c.Name, p.Pid), "err", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// disable keeps the command from starting a process again.
+func (c *Command) disable() {
+	c.killLock.Lock()
+	defer c.killLock.Unlock()
+
+	c.disabled = true
+}
+
+// Kill the kill command, a stopped command is not restarted.
 func (c *Command) Kill() {
+	c.kill(true)
+}
+
+// kill terminates the running process, with stop the restarts end.
+func (c *Command) kill(stop bool) {
 	var v *os.Process
 
 	c.killLock.Lock()
+	if stop && c.stop != nil && !isClosed(c.stop) {
+		close(c.stop)
+	}
+
 	if c.killStarted {
 		c.killLock.Unlock()
 
@@ -223,17 +469,22 @@ func (c *Command) Kill() {
 	}
 
 	v = c.proc
+	done := c.procDone
 	c.killStarted = true
+	// a kill wins over a pending restart
+	c.restart = false
 	c.killLock.Unlock()
 
 	defer func() {
+		c.killLock.Lock()
 		c.killStarted = false
+		c.killLock.Unlock()
 	}()
 
 	if v != nil {
 		slog.Warn(fmt.Sprintf("killing process [%s] [%d]", c.Name, v.Pid))
 
-		if err := terminateProcess(v.Pid); err != nil {
+		if err := c.terminate(v, done); err != nil {
 			slog.Error(fmt.Sprintf("failed to kill process [%s] [%d]", c.Name, v.Pid), "err", err)
 		}
 
@@ -251,3 +502,14 @@ func (c *Command) Restart(ctx context.Context) error {
 
 	return c.Run(ctx)
 }
+
+// SysProcAttr returns the process attributes to run a command as user like
+// the processes of the runner, in its own process group.
+func SysProcAttr(user string) (*syscall.SysProcAttr, error) {
+	return sysProcAttr(user)
+}
+
+// KillProcess kills the process group of a process started with SysProcAttr.
+func KillProcess(pid int) error {
+	return killProcess(pid)
+}
@@ -2,13 +2,25 @@ package runner
 
 import (
 	"fmt"
+	"os"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
-func terminateProcess(pid int) error {
+func terminateProcess(pid int, sig os.Signal) error {
+	v, ok := sig.(syscall.Signal)
+	if !ok {
+		v = syscall.SIGTERM
+	}
+
 	// Signal the process group (-pid), not just the process, so that the process
 	// and all its children are signaled.
-	return syscall.Kill(-pid, syscall.SIGTERM)
+	return syscall.Kill(-pid, v)
+}
+
+func killProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
 }
 
 func sysProcAttr(user string) (*syscall.SysProcAttr, error) {
@@ -33,3 +45,11 @@ func sysProcAttr(user string) (*syscall.SysProcAttr, error) {
 
 	return v, nil
 }
+
+func parseSignal(name string) (os.Signal, error) {
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+
+	return nil, fmt.Errorf("unknown signal %s", name)
+}
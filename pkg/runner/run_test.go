@@ -1,12 +1,14 @@
 package runner
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"io"
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestCommand_Run(t *testing.T) {
@@ -161,3 +163,222 @@ func TestCommand_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestCommand_SignalRestart(t *testing.T) {
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdoutR.Close()
+	defer stdoutW.Close()
+
+	c := &Command{
+		Name:         "trap",
+		Command:      []string{"sh", "-c", `trap "echo hup" HUP; echo start; while true; do sleep 0.1; done`},
+		AllowFailure: true,
+		stdout:       stdoutW,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- c.Run(ctx)
+	}()
+
+	lines := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(stdoutR)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	wantLine := func(want string) {
+		t.Helper()
+
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("Command output = %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Command output timeout waiting %q", want)
+		}
+	}
+
+	wantLine("start")
+
+	hup, err := ParseSignal("hup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Signal(hup); err != nil {
+		t.Fatalf("Command.Signal() error = %v", err)
+	}
+
+	wantLine("hup")
+
+	if err := c.RestartProcess(); err != nil {
+		t.Fatalf("Command.RestartProcess() error = %v", err)
+	}
+
+	wantLine("start")
+
+	select {
+	case err := <-runErr:
+		t.Fatalf("Command.Run() returned after restart: %v", err)
+	default:
+	}
+
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Command.Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Command.Run() not stopped")
+	}
+
+	if err := c.Signal(hup); err == nil {
+		t.Errorf("Command.Signal() expected error when not running")
+	}
+}
+
+func TestCommand_KillStop(t *testing.T) {
+	interrupt, err := ParseSignal("int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		signal  os.Signal
+		want    string
+	}{
+		{
+			name:    "stop signal",
+			command: `trap "echo int; exit 0" INT; echo start; while true; do sleep 0.1; done`,
+			signal:  interrupt,
+			want:    "start\nint\n",
+		},
+		{
+			name:    "sigkill after timeout",
+			command: `trap "" TERM; echo start; while true; do sleep 0.1; done`,
+			want:    "start\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdoutR, stdoutW, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stdoutR.Close()
+
+			c := &Command{
+				Name:         "stop",
+				Command:      []string{"/bin/sh", "-c", tt.command},
+				AllowFailure: true,
+				StopSignal:   tt.signal,
+				StopTimeout:  200 * time.Millisecond,
+				stdout:       stdoutW,
+			}
+
+			runErr := make(chan error, 1)
+			go func() {
+				runErr <- c.Run(context.Background())
+			}()
+
+			scanner := bufio.NewScanner(stdoutR)
+			if !scanner.Scan() {
+				t.Fatal("Command output missing start")
+			}
+
+			killed := make(chan struct{})
+			go func() {
+				c.Kill()
+				close(killed)
+			}()
+
+			select {
+			case <-killed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Command.Kill() not stopped the process")
+			}
+
+			if err := <-runErr; err != nil {
+				t.Fatalf("Command.Run() error = %v", err)
+			}
+
+			stdoutW.Close()
+
+			got := "start\n"
+			for scanner.Scan() {
+				got += scanner.Text() + "\n"
+			}
+
+			if got != tt.want {
+				t.Errorf("Command output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommand_RunLog(t *testing.T) {
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdoutR.Close()
+
+	file := &lockedBuffer{}
+
+	c := &Command{
+		Name:    "app",
+		Command: []string{"sh", "-c", "echo one; echo two >&2"},
+		Log:     Log{Prefix: true, File: file},
+		stdout:  stdoutW,
+		stderr:  stdoutW,
+	}
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Command.Run() error = %v", err)
+	}
+
+	stdoutW.Close()
+
+	got, err := io.ReadAll(stdoutR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"[app] one\n", "[app] two\n"} {
+		if !bytes.Contains(got, []byte(want)) {
+			t.Errorf("Command.Run() output = %q, want %q", got, want)
+		}
+
+		if !bytes.Contains(file.buf.Bytes(), []byte(want)) {
+			t.Errorf("Command.Run() file = %q, want %q", file.buf.String(), want)
+		}
+	}
+}
+
+// lockedBuffer is written by the stdout and stderr filters.
+type lockedBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buf.Write(p)
+}
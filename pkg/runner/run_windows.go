@@ -1,12 +1,17 @@
 package runner
 
 import (
+	"fmt"
 	"os"
 	"syscall"
 )
 
-// Stop stops the command by sending its process group a SIGTERM signal.
-func terminateProcess(pid int) error {
+// terminateProcess kills the process, signals are not supported on windows.
+func terminateProcess(pid int, _ os.Signal) error {
+	return killProcess(pid)
+}
+
+func killProcess(pid int) error {
 	p, err := os.FindProcess(pid)
 	if err != nil {
 		return err
@@ -18,3 +23,14 @@ func terminateProcess(pid int) error {
 func sysProcAttr(_ string) (*syscall.SysProcAttr, error) {
 	return &syscall.SysProcAttr{}, nil
 }
+
+func parseSignal(name string) (os.Signal, error) {
+	switch name {
+	case "SIGKILL":
+		return os.Kill, nil
+	case "SIGINT":
+		return os.Interrupt, nil
+	}
+
+	return nil, fmt.Errorf("signal %s is not supported on windows", name)
+}
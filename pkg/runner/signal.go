@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ParseSignal parses a signal name as SIGHUP, HUP or a signal number.
+func ParseSignal(name string) (os.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	if n, err := strconv.Atoi(name); err == nil {
+		return syscall.Signal(n), nil
+	}
+
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	return parseSignal(name)
+}
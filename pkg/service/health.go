@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/rakunlabs/turna/pkg/render"
+	"github.com/rakunlabs/turna/pkg/runner"
+)
+
+type HealthCheck struct {
+	// Exec is a command run in the service path, env and user, exit code 0
+	// is healthy. Gotemplate enabled.
+	Exec string `cfg:"exec"`
+	// HTTP is a URL, a 2xx response of GET is healthy.
+	HTTP string `cfg:"http"`
+	// TCP is a host:port, a connection is healthy.
+	TCP string `cfg:"tcp"`
+
+	// Interval between checks, default is 5s.
+	Interval time.Duration `cfg:"interval"`
+	// Timeout of a check, default is 3s.
+	Timeout time.Duration `cfg:"timeout"`
+	// Retries is the number of failures in a row to be unhealthy, default is 3.
+	Retries int `cfg:"retries"`
+	// StartPeriod is the time after start where failures are not counted.
+	StartPeriod time.Duration `cfg:"start_period"`
+}
+
+func (h *HealthCheck) healthCheck(path, user string, env []string) (*runner.HealthCheck, error) {
+	var (
+		check func(ctx context.Context) error
+		count int
+	)
+
+	if h.Exec != "" {
+		count++
+
+		rendered, err := render.Execute(h.Exec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render health check exec: %w", err)
+		}
+
+		args, err := shellquote.Split(string(rendered))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse health check exec: %w", err)
+		}
+
+		if len(args) == 0 {
+			return nil, errors.New("health check exec is empty")
+		}
+
+		sys, err := runner.SysProcAttr(user)
+		if err != nil {
+			return nil, fmt.Errorf("health check exec: %w", err)
+		}
+
+		check = func(ctx context.Context) error {
+			cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			cmd.Dir = path
+			cmd.Env = env
+			cmd.SysProcAttr = sys
+			// a timeout kills the children of the check too
+			cmd.Cancel = func() error {
+				return runner.KillProcess(cmd.Process.Pid)
+			}
+
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+			}
+
+			return nil
+		}
+	}
+
+	if h.HTTP != "" {
+		count++
+
+		check = func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.HTTP, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("unexpected status %s", resp.Status)
+			}
+
+			return nil
+		}
+	}
+
+	if h.TCP != "" {
+		count++
+
+		check = func(ctx context.Context) error {
+			var d net.Dialer
+
+			conn, err := d.DialContext(ctx, "tcp", h.TCP)
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		}
+	}
+
+	if count != 1 {
+		return nil, errors.New("health check needs one of exec, http or tcp")
+	}
+
+	return &runner.HealthCheck{
+		Check:       check,
+		Interval:    h.Interval,
+		Timeout:     h.Timeout,
+		Retries:     h.Retries,
+		StartPeriod: h.StartPeriod,
+	}, nil
+}
+
+// parseDepends splits depends like name:healthy to names and conditions.
+func parseDepends(depends []string) ([]string, map[string]string) {
+	names := make([]string, 0, len(depends))
+	conditions := make(map[string]string)
+
+	for _, depend := range depends {
+		name, condition, _ := strings.Cut(depend, ":")
+		names = append(names, name)
+
+		if condition != "" {
+			conditions[name] = condition
+		}
+	}
+
+	return names, conditions
+}
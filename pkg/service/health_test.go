@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDepends(t *testing.T) {
+	names, conditions := parseDepends([]string{"migrate", "db:healthy", "cache:started"})
+
+	if want := []string{"migrate", "db", "cache"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("parseDepends() names = %v, want %v", names, want)
+	}
+
+	if want := map[string]string{"db": "healthy", "cache": "started"}; !reflect.DeepEqual(conditions, want) {
+		t.Errorf("parseDepends() conditions = %v, want %v", conditions, want)
+	}
+}
+
+func TestHealthCheck_healthCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		healthCheck HealthCheck
+		user        string
+		wantErr     bool
+		wantFail    bool
+	}{
+		{name: "exec", healthCheck: HealthCheck{Exec: "/bin/sh -c 'exit 0'"}},
+		{name: "exec fail", healthCheck: HealthCheck{Exec: "/bin/sh -c 'exit 1'"}, wantFail: true},
+		{name: "exec user", healthCheck: HealthCheck{Exec: `/bin/sh -c 'test "$(/usr/bin/id -u)" = 65534'`}, user: "65534"},
+		{name: "exec bad user", healthCheck: HealthCheck{Exec: "/bin/sh -c 'exit 0'"}, user: "1:2:3", wantErr: true},
+		{name: "http", healthCheck: HealthCheck{HTTP: server.URL + "/health"}},
+		{name: "http fail", healthCheck: HealthCheck{HTTP: server.URL + "/"}, wantFail: true},
+		{name: "tcp", healthCheck: HealthCheck{TCP: listener.Addr().String()}},
+		{name: "none", healthCheck: HealthCheck{}, wantErr: true},
+		{name: "two", healthCheck: HealthCheck{Exec: "true", TCP: listener.Addr().String()}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.user != "" && os.Getuid() != 0 {
+				t.Skip("needs root to run as another user")
+			}
+
+			h, err := tt.healthCheck.healthCheck("", tt.user, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HealthCheck.healthCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if err := h.Check(context.Background()); (err != nil) != tt.wantFail {
+				t.Errorf("HealthCheck.Check() error = %v, wantFail %v", err, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_healthCheckTimeout(t *testing.T) {
+	// the child keeps the output open until it is killed
+	h, err := (&HealthCheck{Exec: "/bin/sh -c '/bin/sleep 30 & wait'"}).healthCheck("", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := h.Check(ctx); err == nil {
+		t.Fatal("HealthCheck.Check() error = nil, want timeout")
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("HealthCheck.Check() returned after %s, children not killed", d)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rakunlabs/turna/pkg/filter"
+	"github.com/rakunlabs/turna/pkg/runner"
+)
+
+const defaultLogMaxSize = "10MB"
+
+type Log struct {
+	// Prefix adds [name] to the output lines.
+	Prefix bool `cfg:"prefix"`
+	// JSON writes the output lines as JSON log records with service and
+	// stream attributes.
+	JSON bool `cfg:"json"`
+	// File also writes the output lines to a file.
+	File *LogFile `cfg:"file"`
+}
+
+type LogFile struct {
+	Path string `cfg:"path"`
+	// MaxSize to rotate the file like 10MB, default is 10MB.
+	MaxSize string `cfg:"max_size"`
+	// MaxBackups is the number of rotated files to keep, default is 1.
+	MaxBackups int `cfg:"max_backups"`
+}
+
+func (l *Log) log() (runner.Log, error) {
+	v := runner.Log{
+		Prefix: l.Prefix,
+		JSON:   l.JSON,
+	}
+
+	if l.File == nil {
+		return v, nil
+	}
+
+	if l.File.Path == "" {
+		return v, errors.New("log file path is required")
+	}
+
+	maxSize := l.File.MaxSize
+	if maxSize == "" {
+		maxSize = defaultLogMaxSize
+	}
+
+	size, err := humanize.ParseBytes(maxSize)
+	if err != nil {
+		return v, fmt.Errorf("log file max_size: %w", err)
+	}
+
+	v.File = &filter.RotateWriter{
+		Path:       l.File.Path,
+		MaxSize:    int64(size),
+		MaxBackups: l.File.MaxBackups,
+	}
+
+	return v, nil
+}
@@ -1,14 +1,18 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"regexp"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/kballard/go-shellquote"
 	"github.com/rakunlabs/turna/internal/loader"
+	"github.com/rakunlabs/turna/pkg/filter"
 	"github.com/rakunlabs/turna/pkg/render"
 	"github.com/rakunlabs/turna/pkg/runner"
 )
@@ -32,6 +36,18 @@ type Service struct {
 	Filters [][]byte `cfg:"filters"`
 	// FiltersValues is a list of filter variables path from exported config.
 	FiltersValues []string `cfg:"filters_values"`
+	// FiltersRegex drops the output lines matching one of them.
+	FiltersRegex []string `cfg:"filters_regex"`
+	// FiltersLevel drops JSON output lines with a lower level like warn.
+	FiltersLevel string `cfg:"filters_level"`
+	// Redact masks the values in the output lines with ***.
+	Redact []string `cfg:"redact"`
+	// RedactValues is a list of redact values path from exported config.
+	RedactValues []string `cfg:"redact_values"`
+	// RedactRegex masks the matches, only the groups of a regex with groups.
+	RedactRegex []string `cfg:"redact_regex"`
+	// Log routes the output with a prefix, as JSON or to a rotated file.
+	Log *Log `cfg:"log"`
 
 	// Order is the order of service to run.
 	//
@@ -40,19 +56,76 @@ type Service struct {
 	// Depends is a list of service names to depend on.
 	//
 	// Order is ignoring if depend is set.
+	//
+	// A condition can be added as name:condition, started, healthy or
+	// completed. Default is completed.
 	Depends []string `cfg:"depends"`
 	// AllowFailure is a flag to allow failure of service.
 	AllowFailure bool `cfg:"allow_failure"`
+	// Restart is always, on-failure or never, default is never.
+	//
+	// Services depending on this one are started only once.
+	Restart string `cfg:"restart"`
+	// RestartBackoff is the wait between restarts.
+	RestartBackoff Backoff `cfg:"restart_backoff"`
+	// HealthCheck reports the health of a running service.
+	HealthCheck *HealthCheck `cfg:"health_check"`
+	// StopSignal is sent to stop the service like SIGINT, default is SIGTERM.
+	StopSignal string `cfg:"stop_signal"`
+	// StopTimeout is the wait before SIGKILL, default is 10s.
+	StopTimeout time.Duration `cfg:"stop_timeout"`
+
+	// rules is internal usage to combine filters and redacts with values.
+	rules *filter.Rules
+	mutex sync.RWMutex
+}
+
+// SetFilters builds the output rules again with the loaded values. On error
+// the previous rules are kept.
+func (s *Service) SetFilters() error {
+	rules := &filter.Rules{
+		Contains: slices.Clone(s.Filters),
+		Redact:   make([][]byte, 0, len(s.Redact)),
+	}
+
+	rules.Contains = append(rules.Contains, s.loadedValues(s.FiltersValues)...)
+
+	for _, v := range s.Redact {
+		rules.Redact = append(rules.Redact, []byte(v))
+	}
+
+	rules.Redact = append(rules.Redact, s.loadedValues(s.RedactValues)...)
+
+	var err error
+	if rules.Regex, err = compile(s.FiltersRegex); err != nil {
+		return fmt.Errorf("service %s filters_regex: %w", s.Name, err)
+	}
+
+	if rules.RedactRegex, err = compile(s.RedactRegex); err != nil {
+		return fmt.Errorf("service %s redact_regex: %w", s.Name, err)
+	}
+
+	if s.FiltersLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s.FiltersLevel)); err != nil {
+			return fmt.Errorf("service %s filters_level: %w", s.Name, err)
+		}
 
-	// filters is internal usage to combine filters and filters_values.
-	filters [][]byte
-	mutex   sync.RWMutex
+		rules.MinLevel = &level
+	}
+
+	s.mutex.Lock()
+	s.rules = rules
+	s.mutex.Unlock()
+
+	return nil
 }
 
-func (s *Service) SetFilters() {
-	filterX := s.Filters
+// loadedValues returns the rendered values of the lists in loaded data.
+func (s *Service) loadedValues(paths []string) [][]byte {
+	var values [][]byte
 
-	for _, path := range s.FiltersValues {
+	for _, path := range paths {
 		if vInner, ok := loader.InnerPath(path, render.Data).([]any); ok {
 			for _, val := range vInner {
 				rV, err := render.Execute(val)
@@ -62,29 +135,50 @@ func (s *Service) SetFilters() {
 					continue
 				}
 
-				filterX = append(filterX, []byte(rV))
+				values = append(values, rV)
 			}
 		}
 	}
 
-	s.mutex.Lock()
-	s.filters = filterX
-	s.mutex.Unlock()
+	return values
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		regexes = append(regexes, re)
+	}
+
+	return regexes, nil
+}
+
+func (s *Service) getRules() *filter.Rules {
+	// rules are replaced on dynamic reload
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.rules
 }
 
 func (s *Service) Register() error {
-	filter := func(b []byte) bool {
-		// reading s.filters is not thread safe, so we need to lock it
-		s.mutex.RLock()
-		defer s.mutex.RUnlock()
-
-		for _, f := range s.filters {
-			if bytes.Contains(b, f) {
-				return false
-			}
+	if s.getRules() == nil {
+		if err := s.SetFilters(); err != nil {
+			return err
 		}
+	}
+
+	keep := func(b []byte) bool {
+		return s.getRules().Keep(b)
+	}
 
-		return true
+	rewrite := func(b []byte) []byte {
+		return s.getRules().Rewrite(b)
 	}
 
 	env, err := s.GetEnv(s.Env, s.InheritEnv, s.EnvValues)
@@ -102,16 +196,57 @@ func (s *Service) Register() error {
 		return fmt.Errorf("failed to parse command %s: %w", s.Name, err)
 	}
 
+	depends, conditions := parseDepends(s.Depends)
+
+	var log runner.Log
+	if s.Log != nil {
+		log, err = s.Log.log()
+		if err != nil {
+			return fmt.Errorf("service %s: %w", s.Name, err)
+		}
+	}
+
+	var healthCheck *runner.HealthCheck
+	if s.HealthCheck != nil {
+		healthCheck, err = s.HealthCheck.healthCheck(s.Path, s.User, env)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", s.Name, err)
+		}
+	}
+
+	var stopSignal os.Signal
+	if s.StopSignal != "" {
+		stopSignal, err = runner.ParseSignal(s.StopSignal)
+		if err != nil {
+			return fmt.Errorf("service %s stop_signal: %w", s.Name, err)
+		}
+	}
+
 	c := &runner.Command{
 		Name:         s.Name,
 		Path:         s.Path,
 		Command:      commands,
-		Filter:       filter,
+		Filter:       keep,
 		Env:          env,
 		AllowFailure: s.AllowFailure,
 		Order:        s.Order,
-		Depends:      s.Depends,
+		Depends:      depends,
 		User:         s.User,
+
+		DependsCondition: conditions,
+		HealthCheck:      healthCheck,
+		Log:              log,
+		Rewrite:          rewrite,
+		StopSignal:       stopSignal,
+		StopTimeout:      s.StopTimeout,
+		RestartPolicy: runner.RestartPolicy{
+			Policy:     s.Restart,
+			Delay:      s.RestartBackoff.Delay,
+			MaxDelay:   s.RestartBackoff.MaxDelay,
+			MaxRetries: s.RestartBackoff.MaxRetries,
+			Window:     s.RestartBackoff.Window,
+			Jitter:     s.RestartBackoff.Jitter,
+		},
 	}
 
 	if err := runner.GlobalReg.Add(c); err != nil {
@@ -123,6 +258,19 @@ func (s *Service) Register() error {
 	return nil
 }
 
+type Backoff struct {
+	// Delay before the first restart, doubled on each restart. Default is 1s.
+	Delay time.Duration `cfg:"delay"`
+	// MaxDelay is the maximum wait, default is 1m.
+	MaxDelay time.Duration `cfg:"max_delay"`
+	// MaxRetries is the number of restarts allowed in Window, 0 is unlimited.
+	MaxRetries int `cfg:"max_retries"`
+	// Window is the duration restarts are counted in, 0 counts all restarts.
+	Window time.Duration `cfg:"window"`
+	// Jitter adds a random part of the delay, between 0 and 1.
+	Jitter float64 `cfg:"jitter"`
+}
+
 type Services []Service
 
 func (s Services) Run(ctx context.Context) error {
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rakunlabs/turna/pkg/render"
+)
+
+func TestService_SetFilters(t *testing.T) {
+	render.Data = map[string]any{
+		"vault": map[string]any{"tokens": []any{"tok-1"}},
+	}
+	t.Cleanup(func() { render.Data = make(map[string]any) })
+
+	s := &Service{
+		Name:         "app",
+		Filters:      [][]byte{[]byte("healthz")},
+		FiltersRegex: []string{`^DEBUG`},
+		FiltersLevel: "warn",
+		Redact:       []string{"hunter2"},
+		RedactValues: []string{"vault/tokens"},
+		RedactRegex:  []string{`token=(\S+)`},
+	}
+
+	if err := s.SetFilters(); err != nil {
+		t.Fatalf("Service.SetFilters() error = %v", err)
+	}
+
+	rules := s.getRules()
+
+	for line, want := range map[string]bool{
+		"GET /healthz\n":           false,
+		"DEBUG x\n":                false,
+		`{"level":"info"}` + "\n":  false,
+		`{"level":"error"}` + "\n": true,
+		"uses tok-1 and hunter2\n": true,
+	} {
+		if got := rules.Keep([]byte(line)); got != want {
+			t.Errorf("Rules.Keep(%q) = %v, want %v", line, got, want)
+		}
+	}
+
+	if got := string(rules.Rewrite([]byte("uses tok-1 and hunter2 token=abc\n"))); got != "uses *** and *** token=***\n" {
+		t.Errorf("Rules.Rewrite() = %q", got)
+	}
+
+	// dynamic reload replaces the values
+	render.Data = map[string]any{
+		"vault": map[string]any{"tokens": []any{"tok-2"}},
+	}
+
+	if err := s.SetFilters(); err != nil {
+		t.Fatalf("Service.SetFilters() error = %v", err)
+	}
+
+	if got := string(s.getRules().Rewrite([]byte("tok-1 tok-2\n"))); got != "tok-1 ***\n" {
+		t.Errorf("Rules.Rewrite() after reload = %q", got)
+	}
+
+	if len(s.Filters) != 1 {
+		t.Errorf("Service.SetFilters() changed Filters = %q", s.Filters)
+	}
+
+	// an invalid reload keeps the previous rules
+	s.RedactRegex = []string{`(`}
+
+	if err := s.SetFilters(); err == nil {
+		t.Fatal("Service.SetFilters() error = nil, want redact_regex error")
+	}
+
+	if got := string(s.getRules().Rewrite([]byte("tok-2 token=abc\n"))); got != "*** token=***\n" {
+		t.Errorf("Rules.Rewrite() after invalid reload = %q", got)
+	}
+}
+
+func TestService_SetFiltersError(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *Service
+		wantErr string
+	}{
+		{name: "filters regex", service: &Service{Name: "app", FiltersRegex: []string{`^DEBUG`, `(`}}, wantErr: "service app filters_regex"},
+		{name: "redact regex", service: &Service{Name: "app", RedactRegex: []string{`[`}}, wantErr: "service app redact_regex"},
+		{name: "filters level", service: &Service{Name: "app", FiltersLevel: "loud"}, wantErr: "service app filters_level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.service.SetFilters()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Service.SetFilters() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if tt.service.getRules() != nil {
+				t.Errorf("Service.SetFilters() set rules on error")
+			}
+
+			// services refuse to start
+			if err := tt.service.Register(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Service.Register() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_RegisterStopSignal(t *testing.T) {
+	s := &Service{
+		Name:       "app",
+		Command:    "echo",
+		StopSignal: "nope",
+	}
+
+	if err := s.Register(); err == nil {
+		t.Error("Service.Register() expected error for unknown stop_signal")
+	}
+}